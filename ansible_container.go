@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultAnsibleImage is the image used when ansible-playbook is not installed
+// locally. The digest it resolves to on first use is recorded and every later run
+// uses that digest, so the image cannot change underneath the playbooks.
+const defaultAnsibleImage = "docker.io/alpine/ansible:2.18.1"
+
+// ansibleImagePullTimeout bounds pulling the Ansible image the first time it is used
+const ansibleImagePullTimeout = 10 * time.Minute
+
+// ansibleImageEnv overrides the image used for containerised Ansible runs
+const ansibleImageEnv = "DOCKERIZATHINGINATOR_ANSIBLE_IMAGE"
+
+// containerRuntimes are the local container engines we can run Ansible with, in order of preference
+var containerRuntimes = []string{"docker", "podman"}
+
+// forwardedAnsibleEnv are variables passed from our environment into the Ansible container
+var forwardedAnsibleEnv = []string{
+	"ANSIBLE_STDOUT_CALLBACK",
+	"ANSIBLE_NOCOLOR",
+	"ANSIBLE_FORCE_COLOR",
+	sshPasswordEnv,
+	vaultPasswordEnv,
+}
+
+// detectContainerRuntime returns the first container runtime whose daemon responds, or ""
+func detectContainerRuntime() string {
+	for _, name := range containerRuntimes {
+		if _, err := exec.LookPath(name); err != nil {
+			continue
+		}
+		if _, err := runEnvCommand(name, "info", "--format", "{{.ID}}"); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// ansibleImage returns the container image used for containerised runs
+func ansibleImage() string {
+	if image := os.Getenv(ansibleImageEnv); image != "" {
+		return image
+	}
+	return defaultAnsibleImage
+}
+
+// pinnedAnsibleImage is the digest recorded for an Ansible image tag
+type pinnedAnsibleImage struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// ansibleImagePin guards the file recording the default image's digest
+var ansibleImagePin sync.Mutex
+
+// ansibleImagePinPath is where the default image's digest is recorded
+func ansibleImagePinPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, serviceName, "ansible-image.json")
+}
+
+// resolveAnsibleImage returns the image reference runs use with runtime. An image
+// chosen with the environment variable is used as given; the default image is
+// pulled once and pinned to the digest it resolved to.
+func resolveAnsibleImage(runtime string) (string, error) {
+	image := ansibleImage()
+	if image != defaultAnsibleImage {
+		return image, nil
+	}
+
+	ansibleImagePin.Lock()
+	defer ansibleImagePin.Unlock()
+
+	path := ansibleImagePinPath()
+	var pinned pinnedAnsibleImage
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &pinned) == nil && pinned.Image == image && pinned.Digest != "" {
+		return image + "@" + pinned.Digest, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ansibleImagePullTimeout)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, runtime, "pull", image).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to pull %s: %v: %s", image, err, strings.TrimSpace(string(output)))
+	}
+	digests, err := runEnvCommand(runtime, "image", "inspect", "--format", "{{range .RepoDigests}}{{println .}}{{end}}", image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s: %v", image, err)
+	}
+	digest := ""
+	for _, line := range strings.Split(digests, "\n") {
+		if _, found, ok := strings.Cut(strings.TrimSpace(line), "@"); ok {
+			digest = found
+			break
+		}
+	}
+	if digest == "" {
+		return "", fmt.Errorf("%s has no registry digest to pin", image)
+	}
+
+	pinned = pinnedAnsibleImage{Image: image, Digest: digest}
+	data, err := json.MarshalIndent(pinned, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the pinned Ansible image: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to save the pinned Ansible image: %v", err)
+	}
+	return image + "@" + digest, nil
+}
+
+// ansibleLauncher starts ansible-playbook either locally or inside a container
+type ansibleLauncher struct {
+	runtime string
+	// image is the pinned image reference containerised runs use
+	image  string
+	mounts map[string]string
+	// knownHosts is the container path of the known_hosts file pinning the host key
+	knownHosts string
+}
+
+// resolveAnsibleLauncher prefers a local ansible-playbook and falls back to a container runtime
+func resolveAnsibleLauncher() (*ansibleLauncher, error) {
+	if _, err := exec.LookPath("ansible-playbook"); err == nil {
+		return &ansibleLauncher{}, nil
+	}
+
+	if runtime := detectContainerRuntime(); runtime != "" {
+		image, err := resolveAnsibleImage(runtime)
+		if err != nil {
+			return nil, err
+		}
+		return &ansibleLauncher{runtime: runtime, image: image, mounts: map[string]string{}}, nil
+	}
+
+	return nil, fmt.Errorf("ansible-playbook was not found and no container runtime (docker or podman) is available")
+}
+
+// containerised reports whether runs happen inside a container
+func (l *ansibleLauncher) containerised() bool {
+	return l.runtime != ""
+}
+
+// mount makes a host file or directory visible in the container at containerPath
+func (l *ansibleLauncher) mount(hostPath, containerPath string) {
+	if l.containerised() {
+		l.mounts[hostPath] = containerPath
+	}
+}
+
+// path translates a host path into the path ansible-playbook will see
+func (l *ansibleLauncher) path(hostPath string) string {
+	if !l.containerised() {
+		return hostPath
+	}
+	for source, target := range l.mounts {
+		if hostPath == source {
+			return target
+		}
+		if rel, err := filepath.Rel(source, hostPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return target + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return hostPath
+}
+
+// trustHostKey writes a known_hosts file into dir that trusts only the pinned host
+// key, so containerised runs verify the host just as native and local runs do
+func (l *ansibleLauncher) trustHostKey(dir string) error {
+	if !l.containerised() {
+		return nil
+	}
+	publicKey, err := trustedHostKey()
+	if err != nil {
+		return &hostKeyError{err: err}
+	}
+	path := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(path, append([]byte("* "), ssh.MarshalAuthorizedKey(publicKey)...), 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %v", err)
+	}
+	l.knownHosts = l.path(path)
+	return nil
+}
+
+// command builds the process that runs ansible-playbook with args
+func (l *ansibleLauncher) command(args ...string) *exec.Cmd {
+	if !l.containerised() {
+		return exec.Command("ansible-playbook", args...)
+	}
+
+	runArgs := []string{"run", "--rm", "-i", "-w", "/work"}
+
+	// The container has no writable home of its own, and checks the host against
+	// the pinned key rather than a known_hosts it does not have
+	runArgs = append(runArgs, "-e", "HOME=/tmp")
+	if l.knownHosts != "" {
+		runArgs = append(runArgs,
+			"-e", "ANSIBLE_HOST_KEY_CHECKING=True",
+			"-e", "ANSIBLE_SSH_ARGS=-o ControlMaster=auto -o ControlPersist=60s -o StrictHostKeyChecking=yes -o UserKnownHostsFile="+l.knownHosts)
+	}
+	for _, name := range forwardedAnsibleEnv {
+		runArgs = append(runArgs, "-e", name)
+	}
+
+	if goruntime.GOOS == "linux" {
+		// Share the host network so LAN and mDNS names resolve as they do locally, and
+		// keep file ownership in the work directory so it can be cleaned up afterwards
+		runArgs = append(runArgs, "--network", "host", "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	sources := make([]string, 0, len(l.mounts))
+	for source := range l.mounts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		runArgs = append(runArgs, "-v", fmt.Sprintf("%s:%s", source, l.mounts[source]))
+	}
+
+	runArgs = append(runArgs, l.image, "ansible-playbook")
+	runArgs = append(runArgs, args...)
+
+	return exec.Command(l.runtime, runArgs...)
+}
+
+// envCommand runs a short-lived diagnostic command inside the Ansible image
+func (l *ansibleLauncher) envCommand(name string, args ...string) (string, error) {
+	runArgs := append([]string{"run", "--rm", "-e", "HOME=/tmp", "--entrypoint", name, l.image}, args...)
+	return runEnvCommand(l.runtime, runArgs...)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minAnsibleCoreVersion is the oldest ansible-core the bundled playbooks are tested with
+const minAnsibleCoreVersion = "2.14.0"
+
+// requiredCollection is an Ansible collection the bundled playbooks depend on
+type requiredCollection struct {
+	Name       string
+	MinVersion string
+	UsedFor    string
+}
+
+// requiredCollections lists the collections the bundled playbooks need
+var requiredCollections = []requiredCollection{
+	{
+		Name:       "community.docker",
+		MinVersion: "3.0.0",
+		UsedFor:    "docker_container, docker_network, docker_image and docker_container_info in every stack playbook",
+	},
+	{
+		Name:       "community.general",
+		MinVersion: "6.0.0",
+		UsedFor:    "parted, filesystem and archive in storage configuration and backups",
+	},
+	{
+		Name:       "ansible.posix",
+		MinVersion: "1.4.0",
+		UsedFor:    "mount for USB, NFS and CIFS storage",
+	},
+}
+
+// AnsibleEnvironment describes the Ansible installation playbooks run with: the
+// local one, or the one in the container image when Launcher is a container
+// runtime. Launcher is how playbooks will be started: "local" for ansible-playbook
+// on this computer, the container runtime's name, or empty if they cannot run.
+type AnsibleEnvironment struct {
+	Installed        bool                      `json:"installed"`
+	CoreVersion      string                    `json:"coreVersion,omitempty"`
+	MinimumVersion   string                    `json:"minimumVersion"`
+	VersionOK        bool                      `json:"versionOk"`
+	PythonVersion    string                    `json:"pythonVersion,omitempty"`
+	PythonPath       string                    `json:"pythonPath,omitempty"`
+	Collections      []AnsibleCollectionStatus `json:"collections"`
+	SSHPass          bool                      `json:"sshpass"`
+	ContainerRuntime string                    `json:"containerRuntime,omitempty"`
+	ContainerImage   string                    `json:"containerImage,omitempty"`
+	Launcher         string                    `json:"launcher,omitempty"`
+	Ready            bool                      `json:"ready"`
+	Issues           []EnvironmentIssue        `json:"issues"`
+}
+
+// AnsibleCollectionStatus reports whether a required collection is installed
+type AnsibleCollectionStatus struct {
+	Name       string `json:"name"`
+	Version    string `json:"version,omitempty"`
+	MinVersion string `json:"minVersion"`
+	Installed  bool   `json:"installed"`
+	OK         bool   `json:"ok"`
+	UsedFor    string `json:"usedFor"`
+}
+
+// EnvironmentIssue is a problem with the local environment and how to fix it
+type EnvironmentIssue struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+}
+
+var (
+	coreVersionPattern   = regexp.MustCompile(`^ansible (?:\[core )?([0-9][0-9.]*)`)
+	pythonVersionPattern = regexp.MustCompile(`python version = ([0-9][0-9.]*)[^\n]*?(?:\(([^()]+)\))?\s*$`)
+)
+
+// CheckEnvironment inspects the Ansible installation, its collections and sshpass.
+// Without a local ansible-playbook the checks run inside the container image that
+// playbooks will run in instead.
+func (ar *AnsibleRunner) CheckEnvironment() AnsibleEnvironment {
+	env := AnsibleEnvironment{
+		MinimumVersion: minAnsibleCoreVersion,
+		Collections:    []AnsibleCollectionStatus{},
+		Issues:         []EnvironmentIssue{},
+	}
+
+	// The same choice runs make: a local ansible-playbook, else a container
+	run := runEnvCommand
+	hasSSHPass := func() bool {
+		_, err := exec.LookPath("sshpass")
+		return err == nil
+	}
+	fixes := environmentFixes{
+		ansible: "Upgrade with: python3 -m pip install --upgrade ansible-core (or pipx upgrade ansible-core)",
+		collection: func(name, minVersion string) string {
+			return fmt.Sprintf("Run: ansible-galaxy collection install --upgrade '%s:>=%s'", name, minVersion)
+		},
+		sshpass: installSSHPassHint(),
+	}
+	launcher, launcherErr := resolveAnsibleLauncher()
+	containerised := launcherErr == nil && launcher.containerised()
+	switch {
+	case launcherErr != nil:
+		env.Issues = append(env.Issues, EnvironmentIssue{
+			Severity: "error",
+			Message:  fmt.Sprintf("Ansible cannot run: %v", launcherErr),
+			Fix:      installAnsibleHint(),
+		})
+	case containerised:
+		env.Launcher = launcher.runtime
+		env.ContainerRuntime = launcher.runtime
+		env.ContainerImage = launcher.image
+		run = launcher.envCommand
+		hasSSHPass = func() bool {
+			_, err := launcher.envCommand("sh", "-c", "command -v sshpass")
+			return err == nil
+		}
+		imageFix := fmt.Sprintf("Set %s to an image that provides it, or install Ansible locally: %s", ansibleImageEnv, installAnsibleHint())
+		fixes = environmentFixes{
+			ansible:    imageFix,
+			collection: func(string, string) string { return imageFix },
+			sshpass:    imageFix,
+		}
+		env.Issues = append(env.Issues, EnvironmentIssue{
+			Severity: "warning",
+			Message:  fmt.Sprintf("Ansible is not installed; playbooks will run in the %s image using %s", env.ContainerImage, env.ContainerRuntime),
+			Fix:      installAnsibleHint(),
+		})
+	default:
+		env.Launcher = "local"
+	}
+
+	versionOutput, err := run("ansible", "--version")
+	switch {
+	case launcherErr != nil:
+		// Already reported above
+	case err != nil && containerised:
+		env.Issues = append(env.Issues, EnvironmentIssue{
+			Severity: "error",
+			Message:  fmt.Sprintf("ansible --version failed in the %s image: %v", env.ContainerImage, err),
+			Fix:      fixes.ansible,
+		})
+	case err != nil:
+		env.Issues = append(env.Issues, EnvironmentIssue{
+			Severity: "error",
+			Message:  "Ansible is not installed or not on PATH",
+			Fix:      installAnsibleHint(),
+		})
+	default:
+		env.Installed = true
+		env.CoreVersion, env.PythonVersion, env.PythonPath = parseAnsibleVersion(versionOutput)
+		env.VersionOK = env.CoreVersion != "" && compareVersions(env.CoreVersion, minAnsibleCoreVersion) >= 0
+		if !env.VersionOK {
+			env.Issues = append(env.Issues, EnvironmentIssue{
+				Severity: "error",
+				Message:  fmt.Sprintf("ansible-core %s is older than the required %s", valueOr(env.CoreVersion, "(unknown)"), minAnsibleCoreVersion),
+				Fix:      fixes.ansible,
+			})
+		}
+	}
+
+	installed := map[string]string{}
+	if env.Installed {
+		installed = listCollections(run)
+	}
+	for _, required := range requiredCollections {
+		status := AnsibleCollectionStatus{
+			Name:       required.Name,
+			MinVersion: required.MinVersion,
+			UsedFor:    required.UsedFor,
+		}
+		status.Version, status.Installed = installed[required.Name]
+		status.OK = status.Installed && compareVersions(status.Version, required.MinVersion) >= 0
+		env.Collections = append(env.Collections, status)
+
+		if env.Installed && !status.OK {
+			message := fmt.Sprintf("Collection %s is not installed (needed for %s)", required.Name, required.UsedFor)
+			if status.Installed {
+				message = fmt.Sprintf("Collection %s %s is older than the required %s", required.Name, status.Version, required.MinVersion)
+			}
+			env.Issues = append(env.Issues, EnvironmentIssue{
+				Severity: "error",
+				Message:  message,
+				Fix:      fixes.collection(required.Name, required.MinVersion),
+			})
+		}
+	}
+
+	// Password authentication over the ssh connection plugin needs sshpass
+	if env.Installed && hasSSHPass() {
+		env.SSHPass = true
+	} else if env.Installed {
+		env.Issues = append(env.Issues, EnvironmentIssue{
+			Severity: "error",
+			Message:  "sshpass is not installed, so Ansible cannot log in with a password",
+			Fix:      fixes.sshpass,
+		})
+	}
+
+	env.Ready = env.Installed && env.VersionOK && env.SSHPass
+	for _, collection := range env.Collections {
+		env.Ready = env.Ready && collection.OK
+	}
+
+	return env
+}
+
+// environmentFixes are the fixes suggested for a local install or a container image
+type environmentFixes struct {
+	ansible    string
+	collection func(name, minVersion string) string
+	sshpass    string
+}
+
+// parseAnsibleVersion extracts the core and Python versions from ansible --version
+func parseAnsibleVersion(output string) (core, python, pythonPath string) {
+	lines := strings.Split(output, "\n")
+	if match := coreVersionPattern.FindStringSubmatch(strings.TrimSpace(lines[0])); match != nil {
+		core = match[1]
+	}
+	for _, line := range lines {
+		if match := pythonVersionPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			python = match[1]
+			if strings.HasPrefix(match[2], "/") || strings.Contains(match[2], `\`) {
+				pythonPath = match[2]
+			}
+		}
+	}
+	return core, python, pythonPath
+}
+
+// listCollections returns installed collection versions, keeping the newest of
+// duplicates, using run to call ansible-galaxy locally or in the container image
+func listCollections(run func(string, ...string) (string, error)) map[string]string {
+	collections := map[string]string{}
+
+	output, err := run("ansible-galaxy", "collection", "list", "--format", "json")
+	if err != nil {
+		return collections
+	}
+
+	var byPath map[string]map[string]struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(output), &byPath); err != nil {
+		return collections
+	}
+
+	for _, found := range byPath {
+		for name, info := range found {
+			if current, ok := collections[name]; !ok || compareVersions(info.Version, current) > 0 {
+				collections[name] = info.Version
+			}
+		}
+	}
+	return collections
+}
+
+// runEnvCommand runs a short-lived diagnostic command
+func runEnvCommand(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	return string(output), err
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(strings.TrimFunc(pa[i], func(r rune) bool { return r < '0' || r > '9' }))
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(strings.TrimFunc(pb[i], func(r rune) bool { return r < '0' || r > '9' }))
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// installAnsibleHint returns platform specific instructions for installing Ansible
+func installAnsibleHint() string {
+	switch goruntime.GOOS {
+	case "windows":
+		return "Ansible does not run natively on Windows. Install it inside WSL2 (sudo apt install ansible-core sshpass) or switch to the native executor."
+	case "darwin":
+		return "Install with: brew install ansible (or pipx install ansible-core)"
+	default:
+		return "Install with: pipx install ansible-core (or your distribution's ansible-core package)"
+	}
+}
+
+// installSSHPassHint returns platform specific instructions for installing sshpass
+func installSSHPassHint() string {
+	switch goruntime.GOOS {
+	case "darwin":
+		return "Install with: brew install hudochenkov/sshpass/sshpass"
+	default:
+		return "Install with: sudo apt install sshpass (Debian/Ubuntu) or sudo dnf install sshpass (Fedora)"
+	}
+}
+
+// valueOr returns value, or fallback if value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// GetAnsibleEnvironment reports whether the local Ansible installation can run the bundled playbooks
+func (a *App) GetAnsibleEnvironment() AnsibleEnvironment {
+	return a.ansibleRunner.CheckEnvironment()
+}
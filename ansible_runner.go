@@ -7,6 +7,8 @@ import (
 	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -19,8 +21,14 @@ func NewAnsibleRunner() *AnsibleRunner {
 	return &AnsibleRunner{}
 }
 
-// RunPlaybook executes an Ansible playbook and streams output
+// RunPlaybook executes an Ansible playbook, streams output and reports the result
 func (ar *AnsibleRunner) RunPlaybook(ctx context.Context, cmd *exec.Cmd) error {
+	return ar.finishRun(ctx, ar.runCommand(ctx, cmd))
+}
+
+// runCommand executes ansible-playbook and streams its output, stopping it if ctx ends.
+// The result is not reported, so a failed attempt can be retried.
+func (ar *AnsibleRunner) runCommand(ctx context.Context, cmd *exec.Cmd) error {
 	// Get stdout and stderr pipes
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -37,17 +45,38 @@ func (ar *AnsibleRunner) RunPlaybook(ctx context.Context, cmd *exec.Cmd) error {
 		return fmt.Errorf("failed to start ansible: %v", err)
 	}
 
-	// Stream stdout
-	go ar.streamOutput(ctx, stdout, "stdout")
-	go ar.streamOutput(ctx, stderr, "stderr")
+	// Stop the playbook when the run times out or is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	// Stream stdout and stderr, draining both before Wait closes the pipes
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go func() {
+		defer streams.Done()
+		ar.streamOutput(ctx, stdout, "stdout")
+	}()
+	go func() {
+		defer streams.Done()
+		ar.streamOutput(ctx, stderr, "stderr")
+	}()
+	streams.Wait()
 
 	// Wait for command to complete
 	if err := cmd.Wait(); err != nil {
-		runtime.EventsEmit(ctx, "ansibleError", err.Error())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("ansible playbook failed: %v", err)
 	}
 
-	runtime.EventsEmit(ctx, "ansibleComplete", "Playbook completed successfully")
 	return nil
 }
 
@@ -55,17 +84,35 @@ func (ar *AnsibleRunner) RunPlaybook(ctx context.Context, cmd *exec.Cmd) error {
 func (ar *AnsibleRunner) streamOutput(ctx context.Context, reader io.Reader, streamType string) {
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
-		line := scanner.Text()
-		
-		// Parse Ansible output for better formatting
-		if parsed := ar.parseAnsibleOutput(line); parsed != "" {
-			runtime.EventsEmit(ctx, "ansibleOutput", map[string]interface{}{
-				"type":    streamType,
-				"message": parsed,
-				"raw":     line,
-			})
-		}
+		ar.emitLine(ctx, streamType, scanner.Text())
+	}
+}
+
+// emitLine sends a single line of Ansible-style output to the frontend
+func (ar *AnsibleRunner) emitLine(ctx context.Context, streamType, line string) {
+	if tracker := runTrackerFrom(ctx); tracker != nil {
+		tracker.observe(line)
+	}
+
+	// Nothing reaches the frontend before secrets are masked
+	line = redactorFrom(ctx).redact(line)
+
+	// Parse Ansible output for better formatting
+	parsed := ar.parseAnsibleOutput(line)
+	if parsed == "" {
+		return
+	}
+	if stream := outputStreamFrom(ctx); stream != nil {
+		stream.push(streamType, parsed, line)
+		return
 	}
+	runtime.EventsEmit(ctx, "ansibleOutput", []OutputLine{{
+		Seq:     1,
+		Time:    time.Now(),
+		Type:    streamType,
+		Message: parsed,
+		Raw:     line,
+	}})
 }
 
 // parseAnsibleOutput parses Ansible output for better display
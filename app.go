@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -25,19 +25,38 @@ type App struct {
 	sshClient     *ssh.Client
 	sshMutex      sync.Mutex
 	ansibleRunner *AnsibleRunner
+	backends      map[string]ExecutionBackend
+	history       *runHistory
+	operations    *operationsPolicies
+	locks         *imageLocks
+	registry      *registrySettings
+	custom        *customComponents
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{
+	app := &App{
 		ansibleRunner: NewAnsibleRunner(),
-	}
+		history:       newRunHistory(),
+		operations:    newOperationsPolicies(),
+		locks:         newImageLocks(),
+		registry:      newRegistrySettings(),
+		custom:        newCustomComponents(),
+	}
+	app.registerBackends()
+	return app
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	// Custom components join the catalog before the frontend first loads it
+	a.custom.activate()
+
+	// Remove run directories left behind by a previous crash
+	sweepWorkspaces(false)
 }
 
 // shutdown is called when the app is closing
@@ -48,6 +67,8 @@ func (a *App) shutdown(ctx context.Context) {
 	if a.sshClient != nil {
 		a.sshClient.Close()
 	}
+
+	sweepWorkspaces(true)
 }
 
 // ConnectionResult represents the result of an SSH connection attempt
@@ -60,6 +81,15 @@ type ConnectionResult struct {
 // SecureHostKeyCallback returns a HostKeyCallback that verifies the server key using ssh.FixedHostKey.
 // The trusted host public key is loaded from a local file.
 func SecureHostKeyCallback() (ssh.HostKeyCallback, error) {
+	publicKey, err := trustedHostKey()
+	if err != nil {
+		return nil, err
+	}
+	return ssh.FixedHostKey(publicKey), nil
+}
+
+// trustedHostKey loads the host public key every connection is pinned to
+func trustedHostKey() (ssh.PublicKey, error) {
 	publicKeyBytes, err := os.ReadFile("allowed_hostkey.pub")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read allowed_hostkey.pub: %w", err)
@@ -68,28 +98,29 @@ func SecureHostKeyCallback() (ssh.HostKeyCallback, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse allowed_hostkey.pub: %w", err)
 	}
-	return ssh.FixedHostKey(publicKey), nil
+	return publicKey, nil
 }
 
-// TestSSH tests SSH connection to the Raspberry Pi
-func (a *App) TestSSH(host, user, password string) ConnectionResult {
-	a.sshMutex.Lock()
-	defer a.sshMutex.Unlock()
+// hostKeyError reports that the trusted host key could not be loaded
+type hostKeyError struct {
+	err error
+}
 
-	// Close existing connection if any
-	if a.sshClient != nil {
-		a.sshClient.Close()
-		a.sshClient = nil
-	}
+func (e *hostKeyError) Error() string {
+	return fmt.Sprintf("failed to load trusted host key: %v", e.err)
+}
 
-	// Validate inputs
-	if host == "" || user == "" || password == "" {
-		return ConnectionResult{
-			Success: false,
-			Message: "Host, user, and password are required",
-		}
-	}
+func (e *hostKeyError) Unwrap() error {
+	return e.err
+}
+
+// isLocalHost reports whether the host refers to the local machine
+func isLocalHost(host string) bool {
+	return strings.Contains(host, "localhost") || strings.Contains(host, "127.0.0.1")
+}
 
+// sshAuthMethods returns the authentication methods to offer for a host
+func sshAuthMethods(host, password string) []ssh.AuthMethod {
 	// Support multiple authentication methods
 	var authMethods []ssh.AuthMethod
 	
@@ -97,7 +128,7 @@ func (a *App) TestSSH(host, user, password string) ConnectionResult {
 	authMethods = append(authMethods, ssh.Password(password))
 	
 	// For localhost connections, also try keyboard-interactive
-	if strings.Contains(host, "localhost") || strings.Contains(host, "127.0.0.1") {
+	if isLocalHost(host) {
 		authMethods = append(authMethods, ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
 			answers := make([]string, len(questions))
 			for i := range questions {
@@ -107,19 +138,24 @@ func (a *App) TestSSH(host, user, password string) ConnectionResult {
 		}))
 	}
 
+	return authMethods
+}
+
+// dialSSH opens an SSH connection to the host, retrying with a simpler
+// configuration for servers that reject the extended algorithm lists
+func dialSSH(host, user, password string, timeout time.Duration) (*ssh.Client, error) {
+	authMethods := sshAuthMethods(host, password)
+
 	hostKeyCallback, hkErr := SecureHostKeyCallback()
 	if hkErr != nil {
-		return ConnectionResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to load trusted host key: %v", hkErr),
-		}
+		return nil, &hostKeyError{err: hkErr}
 	}
 
 	config := &ssh.ClientConfig{
 		User:            user,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
-		Timeout:         10 * time.Second, // Increased timeout for better reliability
+		Timeout:         timeout,
 		ClientVersion:   "SSH-2.0-Dockerizathinginator",
 		// Add cipher and kex configurations for better compatibility
 		Config: ssh.Config{
@@ -157,29 +193,53 @@ func (a *App) TestSSH(host, user, password string) ConnectionResult {
 	// If the advanced config fails, try a simpler configuration
 	if err != nil && strings.Contains(err.Error(), "message type") {
 		log.Printf("Advanced SSH config failed, trying simple config: %v", err)
-		hostKeyCallback, hkErr := SecureHostKeyCallback()
-		if hkErr != nil {
-			return ConnectionResult{
-				Success: false,
-				Message: fmt.Sprintf("Failed to load trusted host key: %v", hkErr),
-			}
-		}
-		
 		simpleConfig := &ssh.ClientConfig{
 			User:            user,
 			Auth:            authMethods,
 			HostKeyCallback: hostKeyCallback,
-			Timeout:         10 * time.Second,
+			Timeout:         timeout,
 		}
 		
 		client, err = ssh.Dial("tcp", host, simpleConfig)
 	}
-	
+
+	return client, err
+}
+
+// TestSSH tests SSH connection to the Raspberry Pi
+func (a *App) TestSSH(host, user, password string) ConnectionResult {
+	a.sshMutex.Lock()
+	defer a.sshMutex.Unlock()
+
+	// Close existing connection if any
+	if a.sshClient != nil {
+		a.sshClient.Close()
+		a.sshClient = nil
+	}
+
+	// Validate inputs
+	if host == "" || user == "" || password == "" {
+		return ConnectionResult{
+			Success: false,
+			Message: "Host, user, and password are required",
+		}
+	}
+
+	client, err := dialSSH(host, user, password, a.operations.get(host).connectTimeout())
+
+	var hkErr *hostKeyError
+	if errors.As(err, &hkErr) {
+		return ConnectionResult{
+			Success: false,
+			Message: fmt.Sprintf("Failed to load trusted host key: %v", hkErr.err),
+		}
+	}
+
 	if err != nil {
 		// Provide more specific error messages
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "no supported methods remain") {
-			if strings.Contains(host, "localhost") || strings.Contains(host, "127.0.0.1") {
+			if isLocalHost(host) {
 				return ConnectionResult{
 					Success: false,
 					Message: "SSH authentication failed. For localhost connections, ensure:\n1. SSH server is running (try: sudo systemctl start ssh)\n2. Password authentication is enabled in /etc/ssh/sshd_config\n3. User exists and password is correct",
@@ -364,32 +424,54 @@ type StorageConfig struct {
 	VolumePath   string `json:"volumePath"`
 }
 
-// PrepareUSB prepares USB storage
-func (a *App) PrepareUSB(host, user, password, volumePath string) error {
-	config := StorageConfig{
+// extraVars maps the storage configuration onto the variables configure-storage.yml expects
+func (c StorageConfig) extraVars() map[string]interface{} {
+	vars := map[string]interface{}{
+		"storage_type": c.Type,
+		"volume_path":  c.VolumePath,
+		"mount_point":  c.VolumePath,
+	}
+
+	optional := map[string]string{
+		"usb_device":   c.USBDevice,
+		"nfs_server":   c.NFSServer,
+		"nfs_path":     c.NFSPath,
+		"smb_server":   c.SMBServer,
+		"smb_share":    c.SMBShare,
+		"smb_username": c.SMBUsername,
+		"smb_password": c.SMBPassword,
+	}
+	for key, value := range optional {
+		if value != "" {
+			vars[key] = value
+		}
+	}
+
+	return vars
+}
+
+// usbStorageConfig builds the storage configuration for a USB drive
+func usbStorageConfig(volumePath, usbDevice string) StorageConfig {
+	return StorageConfig{
 		Type:       "usb",
-		USBDevice:  "sda",
+		USBDevice:  usbDevice,
 		VolumePath: volumePath,
 	}
-	
-	return a.runAnsiblePlaybook("configure-storage.yml", host, user, password, config)
 }
 
-// PrepareNetworkNFS prepares NFS storage
-func (a *App) PrepareNetworkNFS(host, user, password, volumePath, nfsServer, nfsPath string) error {
-	config := StorageConfig{
+// nfsStorageConfig builds the storage configuration for an NFS share
+func nfsStorageConfig(volumePath, nfsServer, nfsPath string) StorageConfig {
+	return StorageConfig{
 		Type:       "nfs",
 		NFSServer:  nfsServer,
 		NFSPath:    nfsPath,
 		VolumePath: volumePath,
 	}
-	
-	return a.runAnsiblePlaybook("configure-storage.yml", host, user, password, config)
 }
 
-// PrepareNetworkCIFS prepares CIFS/SMB storage
-func (a *App) PrepareNetworkCIFS(host, user, password, volumePath, smbServer, smbShare, smbUser, smbPass string) error {
-	config := StorageConfig{
+// cifsStorageConfig builds the storage configuration for a CIFS/SMB share
+func cifsStorageConfig(volumePath, smbServer, smbShare, smbUser, smbPass string) StorageConfig {
+	return StorageConfig{
 		Type:        "cifs",
 		SMBServer:   smbServer,
 		SMBShare:    smbShare,
@@ -397,12 +479,45 @@ func (a *App) PrepareNetworkCIFS(host, user, password, volumePath, smbServer, sm
 		SMBPassword: smbPass,
 		VolumePath:  volumePath,
 	}
-	
-	return a.runAnsiblePlaybook("configure-storage.yml", host, user, password, config)
 }
 
-// UpdatePi updates the Raspberry Pi OS
-func (a *App) UpdatePi(host, user, password string) error {
+// PrepareUSB prepares USB storage on the named backend, or the default one if
+// backend is empty
+func (a *App) PrepareUSB(host, user, password, volumePath, usbDevice, backend string) (RunSummary, error) {
+	config := usbStorageConfig(volumePath, usbDevice)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return RunSummary{}, err
+	}
+
+	return a.runAnsiblePlaybook("configure-storage.yml", backend, host, user, password, config.extraVars())
+}
+
+// PrepareNetworkNFS prepares NFS storage on the named backend, or the default one
+func (a *App) PrepareNetworkNFS(host, user, password, volumePath, nfsServer, nfsPath, backend string) (RunSummary, error) {
+	config := nfsStorageConfig(volumePath, nfsServer, nfsPath)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return RunSummary{}, err
+	}
+
+	return a.runAnsiblePlaybook("configure-storage.yml", backend, host, user, password, config.extraVars())
+}
+
+// PrepareNetworkCIFS prepares CIFS/SMB storage on the named backend, or the default one
+func (a *App) PrepareNetworkCIFS(host, user, password, volumePath, smbServer, smbShare, smbUser, smbPass, backend string) (RunSummary, error) {
+	config := cifsStorageConfig(volumePath, smbServer, smbShare, smbUser, smbPass)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return RunSummary{}, err
+	}
+
+	return a.runAnsiblePlaybook("configure-storage.yml", backend, host, user, password, config.extraVars())
+}
+
+// UpdatePi updates the Raspberry Pi OS on the named backend, or the default one
+func (a *App) UpdatePi(host, user, password, backend string) (RunSummary, error) {
+	if err := validateHostInputs(host, user, nil); err != nil {
+		return RunSummary{}, err
+	}
+
 	// Send progress updates to frontend
 	runtime.EventsEmit(a.ctx, "updateProgress", "Starting system update...")
 	
@@ -410,56 +525,145 @@ func (a *App) UpdatePi(host, user, password string) error {
 		"skip_update": false,
 	}
 	
-	return a.runAnsiblePlaybook("main.yml", host, user, password, vars)
+	return a.runAnsiblePlaybook("main.yml", backend, host, user, password, vars)
 }
 
-// InstallDocker installs Docker on the Raspberry Pi
-func (a *App) InstallDocker(host, user, password, volumePath string) error {
+// InstallDocker installs Docker on the Raspberry Pi on the named backend, or the
+// default one
+func (a *App) InstallDocker(host, user, password, volumePath, backend string) (RunSummary, error) {
+	if err := validateHostInputs(host, user, &volumePath); err != nil {
+		return RunSummary{}, err
+	}
+
 	runtime.EventsEmit(a.ctx, "updateProgress", "Installing Docker and required software...")
 	
 	vars := map[string]interface{}{
 		"volume_path": volumePath,
 	}
 	
-	return a.runAnsiblePlaybook("install-docker.yml", host, user, password, vars)
+	return a.runAnsiblePlaybook("install-docker.yml", backend, host, user, password, vars)
 }
 
-// InstallPortainer installs Portainer
-func (a *App) InstallPortainer(host, user, password, volumePath string) error {
+// InstallPortainer installs Portainer on the named backend, or the default one
+func (a *App) InstallPortainer(host, user, password, volumePath, backend string) (RunSummary, error) {
+	if err := validateHostInputs(host, user, &volumePath); err != nil {
+		return RunSummary{}, err
+	}
+
 	runtime.EventsEmit(a.ctx, "updateProgress", "Installing Portainer...")
 	
 	vars := map[string]interface{}{
 		"volume_path": volumePath,
 	}
 	
-	return a.runAnsiblePlaybook("deploy-portainer.yml", host, user, password, vars)
+	return a.runAnsiblePlaybook("deploy-portainer.yml", backend, host, user, password, vars)
 }
 
-// StackConfig represents which stacks and components to deploy
+// StackConfig represents which stacks and components to deploy. Stacks are keyed
+// by catalog stack id, components by their catalog flag and overrides by component id.
 type StackConfig struct {
-	NetworkStack bool              `json:"networkStack"`
-	IoTStack     bool              `json:"iotStack"`
-	MediaStack   bool              `json:"mediaStack"`
-	Components   map[string]bool   `json:"components"`
+	Stacks     map[string]bool               `json:"stacks"`
+	Components map[string]bool               `json:"components"`
+	Overrides  map[string]ComponentOverrides `json:"overrides,omitempty"`
 }
 
-// DeployStacks deploys the selected container stacks
-func (a *App) DeployStacks(host, user, password, volumePath string, config StackConfig) error {
-	runtime.EventsEmit(a.ctx, "updateProgress", "Deploying container stacks...")
-	
+// stackVars converts a StackConfig into playbook extra-vars
+func stackVars(volumePath string, config StackConfig) map[string]interface{} {
 	vars := map[string]interface{}{
-		"volume_path":          volumePath,
-		"deploy_network_stack": config.NetworkStack,
-		"deploy_iot_stack":     config.IoTStack,
-		"deploy_media_stack":   config.MediaStack,
+		"volume_path": volumePath,
 	}
-	
+
+	// Add stack flags
+	for _, stack := range currentCatalog().Stacks {
+		if stack.Flag != "" {
+			vars[stack.Flag] = config.Stacks[stack.ID]
+		}
+	}
+
 	// Add individual component flags
 	for key, value := range config.Components {
 		vars[key] = value
 	}
+
+	if custom := customComponentVars(volumePath); len(custom) > 0 {
+		vars["custom_components"] = custom
+	}
+
+	// Add the variables that apply component overrides
+	for _, component := range currentCatalog().Components {
+		for key, value := range component.overrideVars(config.Overrides[component.ID]) {
+			vars[key] = value
+		}
+	}
+
+	return vars
+}
+
+// DeployStacks deploys the selected container stacks on the named backend, or the
+// default one
+func (a *App) DeployStacks(host, user, password, volumePath string, config StackConfig, backend string) (RunSummary, error) {
+	plan, err := a.PlanDeployment(host, user, password, volumePath, config)
+	if err != nil {
+		return RunSummary{}, err
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", "Deploying container stacks...")
 	
-	return a.runAnsiblePlaybook("main.yml", host, user, password, vars)
+	return a.runAnsiblePlaybook("main.yml", backend, host, user, password, stackVars(volumePath, plan.Config))
+}
+
+// PreviewPrepareUSB shows what PrepareUSB would change without applying it
+func (a *App) PreviewPrepareUSB(host, user, password, volumePath, usbDevice string) (DryRunResult, error) {
+	config := usbStorageConfig(volumePath, usbDevice)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return DryRunResult{}, err
+	}
+
+	return a.previewAnsiblePlaybook("configure-storage.yml", host, user, password, config.extraVars())
+}
+
+// PreviewPrepareNetworkNFS shows what PrepareNetworkNFS would change without applying it
+func (a *App) PreviewPrepareNetworkNFS(host, user, password, volumePath, nfsServer, nfsPath string) (DryRunResult, error) {
+	config := nfsStorageConfig(volumePath, nfsServer, nfsPath)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return DryRunResult{}, err
+	}
+
+	return a.previewAnsiblePlaybook("configure-storage.yml", host, user, password, config.extraVars())
+}
+
+// PreviewPrepareNetworkCIFS shows what PrepareNetworkCIFS would change without applying it
+func (a *App) PreviewPrepareNetworkCIFS(host, user, password, volumePath, smbServer, smbShare, smbUser, smbPass string) (DryRunResult, error) {
+	config := cifsStorageConfig(volumePath, smbServer, smbShare, smbUser, smbPass)
+	if err := validateStorageInputs(host, user, config); err != nil {
+		return DryRunResult{}, err
+	}
+
+	return a.previewAnsiblePlaybook("configure-storage.yml", host, user, password, config.extraVars())
+}
+
+// PreviewInstallDocker shows what InstallDocker would change without applying it
+func (a *App) PreviewInstallDocker(host, user, password, volumePath string) (DryRunResult, error) {
+	if err := validateHostInputs(host, user, &volumePath); err != nil {
+		return DryRunResult{}, err
+	}
+
+	vars := map[string]interface{}{
+		"volume_path": volumePath,
+	}
+
+	return a.previewAnsiblePlaybook("install-docker.yml", host, user, password, vars)
+}
+
+// PreviewDeployStacks shows what DeployStacks would change without applying it
+func (a *App) PreviewDeployStacks(host, user, password, volumePath string, config StackConfig) (DryRunResult, error) {
+	// Preview exactly what DeployStacks would run, dependencies included
+	plan, err := a.PlanDeployment(host, user, password, volumePath, config)
+	if err != nil {
+		return DryRunResult{}, err
+	}
+
+	return a.previewAnsiblePlaybook("main.yml", host, user, password, stackVars(volumePath, plan.Config))
 }
 
 // validatePlaybookName validates that the playbook name is safe to use
@@ -487,61 +691,152 @@ func validatePlaybookName(playbook string) error {
 	return nil
 }
 
-// runAnsiblePlaybook executes an Ansible playbook
-func (a *App) runAnsiblePlaybook(playbook, host, user, password string, extraVars interface{}) error {
+// runAnsiblePlaybook executes a playbook on the named execution backend, or the
+// default one if backend is empty
+func (a *App) runAnsiblePlaybook(playbook, backend, host, user, password string, extraVars map[string]interface{}) (RunSummary, error) {
+	return a.runJob(PlaybookJob{
+		Playbook:  playbook,
+		Host:      host,
+		User:      user,
+		Password:  password,
+		ExtraVars: extraVars,
+		Backend:   backend,
+	}, "")
+}
+
+// previewAnsiblePlaybook runs an Ansible playbook in check and diff mode
+func (a *App) previewAnsiblePlaybook(playbook, host, user, password string, extraVars map[string]interface{}) (DryRunResult, error) {
+	// Check mode is an Ansible feature; the native executor cannot simulate changes
+	if !a.backends[backendAnsible].Available() {
+		return DryRunResult{}, fmt.Errorf("previews require ansible-playbook or a container runtime to run it in")
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Previewing changes for %s...", playbook))
+
+	job := PlaybookJob{
+		Playbook:  playbook,
+		Host:      host,
+		User:      user,
+		Password:  password,
+		ExtraVars: extraVars,
+		Policy:    a.operations.get(host),
+	}
+	cmd, cleanup, err := buildPlaybookCommand(job, "--check", "--diff", fmt.Sprintf("--timeout=%d", int(job.Policy.connectTimeout()/time.Second)))
+	if err != nil {
+		return DryRunResult{}, err
+	}
+	defer cleanup()
+
+	// A check run is bounded by the host's run timeout just like a real one
+	ctx := a.ctx
+	if timeout := job.Policy.runTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	redactor := newJobRedactor(job)
+	result, err := a.ansibleRunner.PreviewPlaybook(ctx, cmd, playbook)
+	if ctx.Err() != nil {
+		err = runContextError(ctx, job.Policy)
+	}
+	return redactor.redactDryRun(result), redactor.redactError(err)
+}
+
+// buildPlaybookCommand prepares the inventory and ansible-playbook command for a run.
+// The returned cleanup function shreds the run's secrets and removes its workspace.
+func buildPlaybookCommand(job PlaybookJob, args ...string) (*exec.Cmd, func(), error) {
 	// Validate playbook name to prevent command injection
-	if err := validatePlaybookName(playbook); err != nil {
-		return fmt.Errorf("playbook validation failed: %v", err)
-	}
-	// Create temporary inventory
-	inventory := fmt.Sprintf(`
-all:
-  children:
-    raspberrypi:
-      hosts:
-        pi:
-          ansible_host: %s
-          ansible_user: %s
-          ansible_password: %s
-`, host, user, password)
-
-	// Write inventory to temp file
-	tempDir := os.TempDir()
-	inventoryFile := filepath.Join(tempDir, "inventory.yml")
-	err := os.WriteFile(inventoryFile, []byte(inventory), 0600)
+	if err := validatePlaybookName(job.Playbook); err != nil {
+		return nil, nil, fmt.Errorf("playbook validation failed: %v", err)
+	}
+
+	// Run locally, or inside a container when Ansible is not installed
+	launcher, err := resolveAnsibleLauncher()
 	if err != nil {
-		return fmt.Errorf("failed to write inventory: %v", err)
+		return nil, nil, err
 	}
-	defer os.Remove(inventoryFile)
 
-	// Convert extraVars to JSON for ansible
-	varsJSON, err := json.Marshal(extraVars)
+	// Materialise the embedded playbooks for this run
+	workspace, err := NewPlaybookWorkspace()
 	if err != nil {
-		return fmt.Errorf("failed to marshal vars: %v", err)
+		return nil, nil, err
 	}
 
-	// Build ansible-playbook command with validated path
-	playbookPath := filepath.Join("ansible", "playbooks", playbook)
-	
-	// Verify the playbook file exists to prevent execution of non-existent files
-	if _, err := os.Stat(playbookPath); err != nil {
-		return fmt.Errorf("playbook file not found: %s", playbookPath)
+	// The inventory lives in the private workspace; the password is passed through
+	// the environment so it never touches disk or the command line
+	inventoryFile, err := writeInventory(workspace.Root(), job.Host, job.User, job.Password != "")
+	if err != nil {
+		workspace.Cleanup()
+		return nil, nil, err
+	}
+
+	// Resolve the playbook inside the workspace so the CWD is irrelevant
+	playbookPath, err := workspace.PlaybookPath(job.Playbook)
+	if job.Custom {
+		playbookPath, err = workspace.CustomPlaybookPath(job.Playbook)
 	}
-	
-	// Use absolute path to prevent path injection
-	absPlaybookPath, err := filepath.Abs(playbookPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %v", err)
+		workspace.Cleanup()
+		return nil, nil, err
 	}
-	
-	cmd := exec.Command("ansible-playbook",
-		"-i", inventoryFile,
-		"-e", string(varsJSON),
-		absPlaybookPath,
-	)
-
-	// Stream output to frontend
-	return a.ansibleRunner.RunPlaybook(a.ctx, cmd)
+
+	// Secret vars go into a per-run vault; the rest into a vars file, tagged
+	// !unsafe like the inventory so no value is ever evaluated as a template
+	plainVars, secretVars := job.splitVars()
+	varsFile, err := writeExtraVars(workspace.Root(), plainVars)
+	if err != nil {
+		workspace.Cleanup()
+		return nil, nil, err
+	}
+
+	launcher.mount(workspace.Root(), "/work")
+	if err := launcher.trustHostKey(workspace.Root()); err != nil {
+		workspace.Cleanup()
+		return nil, nil, err
+	}
+
+	cmdArgs := []string{
+		"-i", launcher.path(inventoryFile),
+		"-e", "@" + launcher.path(varsFile),
+	}
+
+	var vault *runVault
+	cleanup := func() {
+		if vault != nil {
+			vault.Close()
+		}
+		workspace.Cleanup()
+	}
+
+	if len(secretVars) > 0 {
+		vault, err = newRunVault(workspace, secretVars)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		vaultArgs, err := vault.args(workspace, launcher)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cmdArgs = append(cmdArgs, vaultArgs...)
+	}
+
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, launcher.path(playbookPath))
+
+	cmd := launcher.command(cmdArgs...)
+	cmd.Env = append(os.Environ(), sshPasswordEnv+"="+job.Password)
+
+	if vault != nil {
+		if err := vault.attach(cmd); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	return cmd, cleanup, nil
 }
 
 // EmitProgress sends progress updates to the frontend
@@ -0,0 +1,234 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+//go:embed catalog/catalog.json
+var catalogData []byte
+
+// defaultCatalog is the built-in catalog of stacks and components
+var defaultCatalog = mustParseCatalog(catalogData)
+
+// activeCatalog is the built-in catalog with the user's custom components added
+var activeCatalog atomic.Pointer[Catalog]
+
+// currentCatalog returns the catalog deployments are planned and run from
+func currentCatalog() Catalog {
+	if catalog := activeCatalog.Load(); catalog != nil {
+		return *catalog
+	}
+	return defaultCatalog
+}
+
+// CatalogStack is a group of components deployed by one playbook
+type CatalogStack struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Flag is the extra-var that enables the stack in main.yml; empty if always enabled
+	Flag     string   `json:"flag,omitempty"`
+	Playbook string   `json:"playbook"`
+	Dirs     []string `json:"dirs,omitempty"`
+}
+
+// CatalogConflict is a component that must not be deployed alongside another,
+// either at all or only on hosts with less than HostRAMBelowMB of memory
+type CatalogConflict struct {
+	Component      string `json:"component"`
+	HostRAMBelowMB int    `json:"hostRamBelowMb,omitempty"`
+	Reason         string `json:"reason"`
+}
+
+// CatalogComponent is a deployable service made up of one or more containers.
+// URL is where its UI is reached and HealthURL, if set, where its health is
+// checked; both may use the {host} placeholder. Dependencies are declared earlier
+// in the catalog, so catalog order is a valid deployment order.
+type CatalogComponent struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	URL           string            `json:"url,omitempty"`
+	HealthURL     string            `json:"healthUrl,omitempty"`
+	Custom        bool              `json:"custom,omitempty"`
+	Stack         string            `json:"stack"`
+	Flag          string            `json:"flag"`
+	Default       bool              `json:"default"`
+	Image         string            `json:"image"`
+	Ports         []string          `json:"ports"`
+	Volumes       []string          `json:"volumes"`
+	RAMMB         int               `json:"ramMb"`
+	Architectures []string          `json:"architectures"`
+	Dependencies  []string          `json:"dependencies"`
+	Conflicts     []CatalogConflict `json:"conflicts"`
+
+	containers []containerSpec
+}
+
+// Catalog describes every stack and component the app can deploy
+type Catalog struct {
+	Stacks     []CatalogStack     `json:"stacks"`
+	Components []CatalogComponent `json:"components"`
+}
+
+// catalogEntry is a component as written in the catalog file, with its containers
+type catalogEntry struct {
+	CatalogComponent
+	Containers []containerSpec `json:"containers"`
+}
+
+// catalogFile is the layout of the catalog data file
+type catalogFile struct {
+	Stacks     []CatalogStack `json:"stacks"`
+	Components []catalogEntry `json:"components"`
+}
+
+// mustParseCatalog parses the embedded catalog, which is checked at build time
+func mustParseCatalog(data []byte) Catalog {
+	catalog, err := parseCatalog(data)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// parseCatalog reads and checks a catalog file
+func parseCatalog(data []byte) (Catalog, error) {
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Catalog{}, fmt.Errorf("failed to parse catalog: %v", err)
+	}
+
+	catalog := Catalog{Stacks: file.Stacks}
+	for _, entry := range file.Components {
+		component := entry.CatalogComponent
+		component.containers = entry.Containers
+		catalog.Components = append(catalog.Components, component)
+	}
+	return catalog, catalog.check()
+}
+
+// check fills in the fields derived from each component's containers and verifies
+// that stacks, flags and dependencies refer to things that exist
+func (c *Catalog) check() error {
+	stacks := map[string]bool{}
+	for _, stack := range c.Stacks {
+		if stacks[stack.ID] {
+			return fmt.Errorf("catalog: stack %s is declared twice", stack.ID)
+		}
+		stacks[stack.ID] = true
+	}
+
+	ids := map[string]bool{}
+	for i := range c.Components {
+		component := &c.Components[i]
+		if ids[component.ID] {
+			return fmt.Errorf("catalog: component %s is declared twice", component.ID)
+		}
+		ids[component.ID] = true
+		if !stacks[component.Stack] {
+			return fmt.Errorf("catalog: component %s is in unknown stack %s", component.ID, component.Stack)
+		}
+		if component.Flag == "" || len(component.containers) == 0 {
+			return fmt.Errorf("catalog: component %s needs a flag and at least one container", component.ID)
+		}
+
+		component.Ports, component.Volumes = []string{}, []string{}
+		for j := range component.containers {
+			spec := &component.containers[j]
+			spec.Flag, spec.Stack, spec.Default = component.Flag, component.Stack, component.Default
+			if component.Image == "" || spec.Name == component.ID {
+				component.Image = spec.Image
+			}
+			component.Ports = append(component.Ports, spec.Ports...)
+			component.Volumes = append(component.Volumes, spec.Volumes...)
+		}
+		if component.Architectures == nil {
+			component.Architectures = []string{}
+		}
+		if component.Dependencies == nil {
+			component.Dependencies = []string{}
+		}
+		if component.Conflicts == nil {
+			component.Conflicts = []CatalogConflict{}
+		}
+	}
+
+	declared := map[string]bool{}
+	for _, component := range c.Components {
+		for _, dependency := range component.Dependencies {
+			if !ids[dependency] {
+				return fmt.Errorf("catalog: component %s depends on unknown component %s", component.ID, dependency)
+			}
+			if !declared[dependency] {
+				return fmt.Errorf("catalog: component %s must be declared after its dependency %s", component.ID, dependency)
+			}
+		}
+		for _, conflict := range component.Conflicts {
+			if !ids[conflict.Component] || conflict.Component == component.ID {
+				return fmt.Errorf("catalog: component %s conflicts with unknown component %s", component.ID, conflict.Component)
+			}
+		}
+		declared[component.ID] = true
+	}
+	return nil
+}
+
+// withCustom returns the catalog with custom components appended to the custom stack
+func (c Catalog) withCustom(custom []CustomComponent) (Catalog, error) {
+	merged := Catalog{Stacks: append([]CatalogStack{}, c.Stacks...)}
+	for _, component := range c.Components {
+		component.containers = append([]containerSpec{}, component.containers...)
+		merged.Components = append(merged.Components, component)
+	}
+	for _, component := range custom {
+		merged.Components = append(merged.Components, component.catalogComponent())
+	}
+	return merged, merged.check()
+}
+
+// containerSpecs returns every component's containers in catalog order
+func (c Catalog) containerSpecs() []containerSpec {
+	var specs []containerSpec
+	for _, component := range c.Components {
+		specs = append(specs, component.containers...)
+	}
+	return specs
+}
+
+// stackDirs returns the shared directories each stack's playbook creates
+func (c Catalog) stackDirs() map[string][]string {
+	dirs := map[string][]string{}
+	for _, stack := range c.Stacks {
+		dirs[stack.ID] = stack.Dirs
+	}
+	return dirs
+}
+
+// component returns the component with the given id
+func (c Catalog) component(id string) (CatalogComponent, bool) {
+	for _, component := range c.Components {
+		if component.ID == id {
+			return component, true
+		}
+	}
+	return CatalogComponent{}, false
+}
+
+// stack returns the stack with the given id
+func (c Catalog) stack(id string) (CatalogStack, bool) {
+	for _, stack := range c.Stacks {
+		if stack.ID == id {
+			return stack, true
+		}
+	}
+	return CatalogStack{}, false
+}
+
+// GetCatalog returns every stack and component that can be deployed
+func (a *App) GetCatalog() Catalog {
+	return currentCatalog()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+)
+
+// withCustomStore points the custom component store at a temporary config
+// directory and restores the built-in catalog afterwards
+func withCustomStore(t *testing.T) *App {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { activeCatalog.Store(nil) })
+	return &App{custom: newCustomComponents(), operations: newOperationsPolicies()}
+}
+
+func TestCurrentCatalogDefaultsToBuiltIn(t *testing.T) {
+	activeCatalog.Store(nil)
+	if got, want := len(currentCatalog().Components), len(defaultCatalog.Components); got != want {
+		t.Fatalf("currentCatalog() has %d components, want %d", got, want)
+	}
+}
+
+func TestActivateFallsBackOnUnreadableStore(t *testing.T) {
+	app := withCustomStore(t)
+	app.custom.path = t.TempDir() // a directory cannot be read as the store
+
+	app.custom.activate()
+	if activeCatalog.Load() == nil {
+		t.Fatal("activate() left no catalog stored")
+	}
+	if got, want := len(currentCatalog().Components), len(defaultCatalog.Components); got != want {
+		t.Errorf("currentCatalog() has %d components, want the %d built-in ones", got, want)
+	}
+}
+
+func TestCustomComponentJoinsCatalogAndPlan(t *testing.T) {
+	app := withCustomStore(t)
+	app.custom.activate()
+
+	component := CustomComponent{
+		ID:      "sensor-bridge",
+		Name:    "Sensor Bridge",
+		Image:   "registry.local:5000/sensor-bridge:1.2",
+		Ports:   []string{"9100:9100"},
+		Volumes: []string{"{data}/sensor-bridge:/data"},
+		RAMMB:   64,
+	}
+	if err := app.AddCustomComponent(component); err != nil {
+		t.Fatalf("AddCustomComponent() error = %v", err)
+	}
+
+	found, ok := app.GetCatalog().component("sensor-bridge")
+	if !ok {
+		t.Fatal("GetCatalog() does not list the custom component")
+	}
+	if !found.Custom || found.Stack != customStack {
+		t.Errorf("custom component = %+v, want it in the %s stack", found, customStack)
+	}
+
+	config := StackConfig{
+		Stacks:     map[string]bool{customStack: true},
+		Components: map[string]bool{customFlag("sensor-bridge"): true},
+	}
+	plan, err := app.PlanDeployment("127.0.0.1", "pi", "", "/mnt/docker", config)
+	if err != nil {
+		t.Fatalf("PlanDeployment() error = %v", err)
+	}
+	planned := false
+	for _, step := range plan.Steps {
+		if step.Component == "sensor-bridge" {
+			planned = true
+		}
+	}
+	if !planned || !plan.Config.Components[customFlag("sensor-bridge")] {
+		t.Errorf("PlanDeployment() steps = %+v, want sensor-bridge deployed", plan.Steps)
+	}
+
+	if err := app.RemoveCustomComponent("sensor-bridge"); err != nil {
+		t.Fatalf("RemoveCustomComponent() error = %v", err)
+	}
+	if _, ok := app.GetCatalog().component("sensor-bridge"); ok {
+		t.Error("GetCatalog() still lists the removed custom component")
+	}
+}
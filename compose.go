@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Actions a compose deployment can apply
+const (
+	composeUp   = "up"
+	composePull = "pull"
+	composeDown = "down"
+)
+
+// composePlaybook labels compose deployments in the run history
+const composePlaybook = "docker-compose.yml"
+
+// composeRoot is where each stack's compose project lives on the host
+const composeRoot = "{volume}/compose"
+
+// envLinePattern matches a VAR=value line of a .env file
+var envLinePattern = regexp.MustCompile(`^([A-Z_][A-Z0-9_]*)=(.*)$`)
+
+// ComposeProject is the compose file and .env rendered for one stack
+type ComposeProject struct {
+	Stack   string `json:"stack"`
+	Dir     string `json:"dir"`
+	Compose string `json:"compose"`
+	Env     string `json:"env"`
+}
+
+// composeVarName returns the .env variable holding a generated secret
+func composeVarName(secret string) string {
+	return strings.ToUpper(secret)
+}
+
+// renderComposeProject renders a stack's containers as a compose project. expand
+// resolves path and host placeholders; secrets are written to .env by secret and
+// referenced from the compose file as ${NAME} so they never appear in it.
+func renderComposeProject(stack, dir string, specs []containerSpec, expand, secret func(string) string) ComposeProject {
+	env := map[string]string{}
+	value := func(s string) string {
+		// Compose interpolates $, so literal dollars are doubled
+		s = strings.ReplaceAll(s, "$", "$$")
+		s = placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+			key := match[1 : len(match)-1]
+			if name := strings.TrimPrefix(key, "secret:"); name != key {
+				variable := composeVarName(name)
+				env[variable] = secret(name)
+				return "${" + variable + "}"
+			}
+			return strings.ReplaceAll(expand(match), "$", "$$")
+		})
+		return yamlScalar(s, true)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by Dockerizathinginator; changes are overwritten on the next deployment\n")
+	fmt.Fprintf(&b, "name: %s\n", yamlScalar(stack, true))
+	b.WriteString("services:\n")
+
+	sharedNetwork := false
+	for _, spec := range specs {
+		restart := spec.Restart
+		if restart == "" {
+			restart = "unless-stopped"
+		}
+
+		fmt.Fprintf(&b, "  %s:\n", spec.Name)
+		fmt.Fprintf(&b, "    image: %s\n", value(spec.Image))
+		fmt.Fprintf(&b, "    container_name: %s\n", value(spec.Name))
+		fmt.Fprintf(&b, "    restart: %s\n", value(restart))
+		if spec.NetworkMode != "" {
+			fmt.Fprintf(&b, "    network_mode: %s\n", value(spec.NetworkMode))
+		} else {
+			b.WriteString("    networks:\n      - docker_network\n")
+			sharedNetwork = true
+		}
+		if spec.Memory != "" {
+			fmt.Fprintf(&b, "    mem_limit: %s\n", value(spec.Memory))
+		}
+		writeComposeList(&b, "ports", spec.Ports, value)
+		writeComposeMap(&b, "environment", spec.Env, value)
+		writeComposeList(&b, "volumes", spec.Volumes, value)
+		writeComposeList(&b, "devices", spec.Devices, value)
+		writeComposeList(&b, "cap_add", spec.Capabilities, value)
+		writeComposeMap(&b, "sysctls", spec.Sysctls, value)
+		writeComposeList(&b, "command", spec.Command, value)
+		writeComposeMap(&b, "labels", map[string]string{
+			"com.dockerizathinginator.managed": "true",
+			"com.dockerizathinginator.stack":   spec.Stack,
+			"com.dockerizathinginator.service": spec.Name,
+		}, value)
+	}
+
+	if sharedNetwork {
+		b.WriteString("networks:\n  docker_network:\n    external: true\n")
+	}
+
+	var envFile strings.Builder
+	for _, key := range sortedKeys(env) {
+		fmt.Fprintf(&envFile, "%s=%s\n", key, env[key])
+	}
+
+	return ComposeProject{
+		Stack:   stack,
+		Dir:     dir,
+		Compose: b.String(),
+		Env:     envFile.String(),
+	}
+}
+
+// writeComposeList writes a YAML list field, omitting it when empty
+func writeComposeList(b *strings.Builder, field string, items []string, value func(string) string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "    %s:\n", field)
+	for _, item := range items {
+		fmt.Fprintf(b, "      - %s\n", value(item))
+	}
+}
+
+// writeComposeMap writes a YAML mapping field in key order, omitting it when empty
+func writeComposeMap(b *strings.Builder, field string, items map[string]string, value func(string) string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "    %s:\n", field)
+	for _, key := range sortedKeys(items) {
+		fmt.Fprintf(b, "      %s: %s\n", yamlScalar(key, true), value(items[key]))
+	}
+}
+
+// parseEnvFile reads VAR=value lines, ignoring anything else
+func parseEnvFile(content string) map[string]string {
+	env := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		if match := envLinePattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			env[match[1]] = match[2]
+		}
+	}
+	return env
+}
+
+// composeStacks returns the stacks a job deploys and the enabled containers in each
+func (r *nativeRun) composeStacks() ([]string, map[string][]containerSpec) {
+	containers := map[string][]containerSpec{}
+	for _, spec := range currentCatalog().containerSpecs() {
+		if r.boolVar(spec.Flag, spec.Default) {
+			containers[spec.Stack] = append(containers[spec.Stack], r.customize(spec))
+		}
+	}
+
+	var stacks []string
+	for _, stack := range currentCatalog().Stacks {
+		// Stacks without a flag, like Portainer, are deployed whenever a component is enabled
+		if len(containers[stack.ID]) > 0 && (stack.Flag == "" || r.boolVar(stack.Flag, false)) {
+			stacks = append(stacks, stack.ID)
+		}
+	}
+	return stacks, containers
+}
+
+// composeDir returns the directory holding a stack's compose project
+func (r *nativeRun) composeDir(stack string) string {
+	return path.Join(r.expand(composeRoot), stack)
+}
+
+// loadComposeSecrets reuses the secrets in a stack's existing .env, so redeploying
+// never changes the passwords of services that already store data
+func (r *nativeRun) loadComposeSecrets(stack string) {
+	content, err := r.sudo("cat " + shellQuote(path.Join(r.composeDir(stack), ".env")))
+	if err != nil {
+		return
+	}
+	for variable, value := range parseEnvFile(content) {
+		// Secret names are lower case, so the variable name maps straight back
+		name := strings.ToLower(variable)
+		if _, ok := r.secrets[name]; ok || value == "" {
+			continue
+		}
+		r.secrets[name] = value
+		if redactor := redactorFrom(r.ctx); redactor != nil {
+			redactor.add(value)
+		}
+	}
+}
+
+// composeDeploy applies the job's compose action to every selected stack
+func (r *nativeRun) composeDeploy() error {
+	action := r.job.Compose
+	r.play(fmt.Sprintf("Docker Compose %s on Raspberry Pi", action))
+
+	stacks, containers := r.composeStacks()
+	if len(stacks) == 0 {
+		r.line("No stacks selected")
+		return nil
+	}
+
+	if action != composeDown {
+		if err := r.task("Ensure Docker network exists", r.ensureDockerNetwork); err != nil {
+			return err
+		}
+	}
+
+	for _, stack := range stacks {
+		var err error
+		switch action {
+		case composeDown:
+			err = r.composeStackDown(stack)
+		default:
+			err = r.composeStackUp(stack, containers[stack], action == composePull)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeStackUp writes a stack's project and pulls its images, then starts it unless pullOnly
+func (r *nativeRun) composeStackUp(stack string, specs []containerSpec, pullOnly bool) error {
+	if err := r.task(fmt.Sprintf("Create directories for %s stack", stack), func() (bool, error) {
+		return r.mkdirs("root", r.expandAll(currentCatalog().stackDirs()[stack])...)
+	}); err != nil {
+		return err
+	}
+	r.loadComposeSecrets(stack)
+	for _, spec := range specs {
+		if err := r.prepareContainer(spec); err != nil {
+			return err
+		}
+	}
+
+	project := renderComposeProject(stack, r.composeDir(stack), specs, r.expand, r.secret)
+	if err := r.task(fmt.Sprintf("Write %s compose project", stack), func() (bool, error) {
+		envChanged, err := r.writeFile(templateFile{Path: path.Join(project.Dir, ".env"), Content: project.Env, Mode: "0600"})
+		if err != nil {
+			return false, err
+		}
+		composeChanged, err := r.writeFile(templateFile{Path: path.Join(project.Dir, "docker-compose.yml"), Content: project.Compose, Mode: "0644"})
+		return envChanged || composeChanged, err
+	}); err != nil {
+		return err
+	}
+
+	compose := "cd " + shellQuote(project.Dir) + " && docker compose"
+	if err := r.task(fmt.Sprintf("Pull %s stack images", stack), func() (bool, error) {
+		out, err := r.sudo(compose + " pull 2>&1")
+		return strings.Contains(out, "Pulled") || strings.Contains(out, "Downloaded newer image"), err
+	}); err != nil {
+		return err
+	}
+	if pullOnly {
+		return nil
+	}
+
+	// Containers created by docker run would clash with the compose service names
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = shellQuote(spec.Name)
+	}
+	if err := r.task(fmt.Sprintf("Adopt existing %s containers", stack), func() (bool, error) {
+		out, err := r.sudo(fmt.Sprintf(`for c in %s; do p=$(docker inspect -f '{{index .Config.Labels "com.docker.compose.project"}}' "$c" 2>/dev/null) || continue; [ "$p" = %s ] || { docker rm -f "$c" >/dev/null && echo removed; }; done`,
+			strings.Join(names, " "), shellQuote(stack)))
+		return strings.Contains(out, "removed"), err
+	}); err != nil {
+		return err
+	}
+
+	if err := r.task(fmt.Sprintf("Start %s stack", stack), func() (bool, error) {
+		out, err := r.sudo(compose + " up -d --remove-orphans 2>&1")
+		for _, word := range []string{"Created", "Recreated", "Started", "Removed"} {
+			if strings.Contains(out, word) {
+				return true, err
+			}
+		}
+		return false, err
+	}); err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if err := r.finishContainer(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeStackDown stops and removes a stack's compose project, keeping its data
+func (r *nativeRun) composeStackDown(stack string) error {
+	dir := r.composeDir(stack)
+	return r.task(fmt.Sprintf("Stop %s stack", stack), func() (bool, error) {
+		if _, err := r.sudo("test -f " + shellQuote(path.Join(dir, "docker-compose.yml"))); err != nil {
+			return false, nil
+		}
+		out, err := r.sudo("cd " + shellQuote(dir) + " && docker compose down --remove-orphans 2>&1")
+		return strings.Contains(out, "Removed"), err
+	})
+}
+
+// validComposeAction reports whether action is one DeployStacksCompose accepts
+func validComposeAction(action string) bool {
+	return action == composeUp || action == composePull || action == composeDown
+}
+
+// RenderComposeProjects returns the compose file and .env each selected stack would get.
+// Generated secrets are shown as placeholders.
+func (a *App) RenderComposeProjects(host, volumePath string, config StackConfig) ([]ComposeProject, error) {
+	v := &ValidationError{}
+	if !validHost(host) {
+		v.add("host", "must be a host name or IP address")
+	}
+	validateVolumePath(v, "volumePath", volumePath)
+	config.validate(v)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	plan, err := resolveDeployment(config, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	run := &nativeRun{
+		job:     PlaybookJob{Host: host},
+		vars:    stackVars(volumePath, plan.Config),
+		secrets: map[string]string{},
+	}
+	stacks, containers := run.composeStacks()
+
+	projects := []ComposeProject{}
+	for _, stack := range stacks {
+		projects = append(projects, renderComposeProject(stack, run.composeDir(stack), containers[stack], run.expand, func(string) string {
+			return "<generated on deploy>"
+		}))
+	}
+	return projects, nil
+}
+
+// DeployStacksCompose renders the selected stacks as Docker Compose projects under the
+// volume path and applies them with docker compose: up (pull and start), pull or down
+func (a *App) DeployStacksCompose(host, user, password, volumePath string, config StackConfig, action string) (RunSummary, error) {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateVolumePath(v, "volumePath", volumePath)
+	config.validate(v)
+	if !validComposeAction(action) {
+		v.add("action", "must be up, pull or down")
+	}
+	if err := v.err(); err != nil {
+		return RunSummary{}, err
+	}
+
+	// Taking stacks down removes exactly what was selected; anything else deploys the resolved plan
+	if action != composeDown {
+		plan, err := a.PlanDeployment(host, user, password, volumePath, config)
+		if err != nil {
+			return RunSummary{}, err
+		}
+		config = plan.Config
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Running docker compose %s...", action))
+
+	return a.runJob(PlaybookJob{
+		Playbook:  composePlaybook,
+		Host:      host,
+		User:      user,
+		Password:  password,
+		ExtraVars: stackVars(volumePath, config),
+		Compose:   action,
+	}, "")
+}
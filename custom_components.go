@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// customStack is the catalog stack user-defined components are deployed in
+const customStack = "custom"
+
+var (
+	customIDPattern = regexp.MustCompile(`^[a-z][a-z0-9]*(?:-[a-z0-9]+)*$`)
+	restartPolicies = []string{"no", "always", "unless-stopped", "on-failure"}
+	// customArchitectures are the Docker platforms a custom image can declare
+	customArchitectures = []string{"arm64", "armhf", "amd64"}
+	// customPathPlaceholders are the placeholders custom volumes and env values may use
+	customPathPlaceholders = []string{"volume", "data", "config", "media"}
+)
+
+// CustomComponent is a component defined by the user rather than the built-in
+// catalog. It runs a single container named after its id. Ports are written as
+// "8080:80" or "5353:53/udp" and volumes as "{data}/app:/data", optionally with
+// ":ro"; host paths may start with {volume}, {data}, {config} or {media}.
+// HealthURL is probed to decide whether an upgrade succeeded.
+type CustomComponent struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Image         string            `json:"image"`
+	Ports         []string          `json:"ports"`
+	Env           map[string]string `json:"env"`
+	Volumes       []string          `json:"volumes"`
+	Restart       string            `json:"restart"`
+	HealthURL     string            `json:"healthUrl"`
+	Architectures []string          `json:"architectures"`
+	RAMMB         int               `json:"ramMb"`
+}
+
+// customFlag is the extra-var that selects a custom component
+func customFlag(id string) string {
+	return "deploy_custom_" + strings.ReplaceAll(id, "-", "_")
+}
+
+// catalogComponent turns the definition into a catalog component in the custom stack
+func (c CustomComponent) catalogComponent() CatalogComponent {
+	spec := containerSpec{
+		Name:    c.ID,
+		Image:   c.Image,
+		Ports:   c.Ports,
+		Env:     c.Env,
+		Volumes: c.Volumes,
+		Restart: c.Restart,
+	}
+	for _, volume := range c.Volumes {
+		source := strings.SplitN(volume, ":", 2)[0]
+		if strings.HasPrefix(source, "{data}/") || strings.HasPrefix(source, "{config}/") {
+			spec.Dirs = append(spec.Dirs, source)
+		}
+	}
+
+	return CatalogComponent{
+		ID:            c.ID,
+		Name:          c.Name,
+		Description:   c.Description,
+		HealthURL:     c.HealthURL,
+		Custom:        true,
+		Stack:         customStack,
+		Flag:          customFlag(c.ID),
+		RAMMB:         c.RAMMB,
+		Architectures: c.Architectures,
+		containers:    []containerSpec{spec},
+	}
+}
+
+// onlyPathPlaceholders reports whether every placeholder in s is a host path one
+func onlyPathPlaceholders(s string) bool {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+		if !containsString(customPathPlaceholders, match[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// validHostPath reports whether a volume source is an absolute path, or one under
+// a path placeholder, that is clean and not the root directory
+func validHostPath(source string) bool {
+	rest := source
+	for _, placeholder := range customPathPlaceholders {
+		if prefix := "{" + placeholder + "}"; strings.HasPrefix(source, prefix) {
+			rest = strings.TrimPrefix(source, prefix)
+			if rest == "" {
+				return true
+			}
+			break
+		}
+	}
+	return strings.HasPrefix(rest, "/") && rest != "/" && path.Clean(rest) == rest && !strings.ContainsAny(rest, "{}")
+}
+
+// validate checks a custom component definition. Ids must not clash with a
+// built-in component or container.
+func (c CustomComponent) validate(v *ValidationError) {
+	switch _, builtIn := defaultCatalog.component(c.ID); {
+	case len(c.ID) > 40 || !customIDPattern.MatchString(c.ID):
+		v.add("id", "must be lowercase letters, digits and single hyphens, starting with a letter")
+	case builtIn:
+		v.add("id", "%s is a built-in component", c.ID)
+	default:
+		for _, spec := range defaultCatalog.containerSpecs() {
+			if spec.Name == c.ID {
+				v.add("id", "%s is the name of a built-in container", c.ID)
+			}
+		}
+	}
+
+	if strings.TrimSpace(c.Name) == "" || len(c.Name) > 60 || strings.ContainsAny(c.Name, "\n\r") {
+		v.add("name", "is required and must be a single line of at most 60 characters")
+	}
+	if len(c.Description) > 200 || strings.ContainsAny(c.Description, "\n\r") {
+		v.add("description", "must be a single line of at most 200 characters")
+	}
+
+	repository, tag := splitImage(c.Image)
+	if !imageRepoPattern.MatchString(repository) || !imageTagPattern.MatchString(tag) {
+		v.add("image", "must be an image such as registry.local:5000/sensor-bridge:1.2")
+	}
+
+	hostPorts := map[string]bool{}
+	for i, mapping := range c.Ports {
+		field := fmt.Sprintf("ports[%d]", i)
+		key, target := parsePortMapping(mapping)
+		hostPort, hostProtocol, hostErr := parsePortKey(key)
+		_, targetProtocol, targetErr := parsePortKey(target)
+		published := fmt.Sprintf("%d/%s", hostPort, hostProtocol)
+		switch {
+		case !strings.Contains(mapping, ":") || hostErr != nil || targetErr != nil || hostProtocol != targetProtocol:
+			v.add(field, "must be a port mapping such as 8080:80 or 5353:53/udp")
+		case hostPorts[published]:
+			v.add(field, "publishes host port %s twice", published)
+		}
+		hostPorts[published] = true
+	}
+
+	for _, name := range sortedKeys(c.Env) {
+		switch value := c.Env[name]; {
+		case !envNamePattern.MatchString(name):
+			v.add("env."+name, "is not a valid environment variable name")
+		case strings.ContainsAny(value, "\n\r"):
+			v.add("env."+name, "must be a single line")
+		case templated(value):
+			v.add("env."+name, "must not contain {{, {%% or {#")
+		case !onlyPathPlaceholders(value):
+			v.add("env."+name, "may only use the {volume}, {data}, {config} and {media} placeholders")
+		}
+	}
+
+	for i, volume := range c.Volumes {
+		field := fmt.Sprintf("volumes[%d]", i)
+		parts := strings.Split(volume, ":")
+		switch {
+		case len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw"):
+			v.add(field, "must be source:target, optionally followed by :ro")
+		case templated(volume) || strings.ContainsAny(volume, ",\n\r") || !validHostPath(parts[0]):
+			v.add(field, "source must be an absolute path or start with {volume}, {data}, {config} or {media}")
+		case !strings.HasPrefix(parts[1], "/") || path.Clean(parts[1]) != parts[1] || strings.ContainsAny(parts[1], "{}"):
+			v.add(field, "target must be an absolute path inside the container")
+		}
+	}
+
+	if c.Restart != "" && !containsString(restartPolicies, c.Restart) {
+		v.add("restart", "must be one of %s", strings.Join(restartPolicies, ", "))
+	}
+
+	if c.HealthURL != "" {
+		u, err := url.Parse(strings.ReplaceAll(c.HealthURL, "{host}", "localhost"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || templated(c.HealthURL) {
+			v.add("healthUrl", "must be an http or https URL such as http://{host}:8080/health")
+		} else if port := u.Port(); port != "" {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				v.add("healthUrl", "port must be between 1 and 65535")
+			}
+		}
+	}
+
+	for _, arch := range c.Architectures {
+		if !containsString(customArchitectures, arch) {
+			v.add("architectures", "must be %s", strings.Join(customArchitectures, ", "))
+			break
+		}
+	}
+
+	if c.RAMMB < 0 || c.RAMMB > 65536 {
+		v.add("ramMb", "must be between 0 and 65536")
+	}
+}
+
+// customComponentVars describes the custom components for deploy-custom-stack.yml,
+// with their host paths expanded for volumePath
+func customComponentVars(volumePath string) []interface{} {
+	run := &nativeRun{vars: map[string]interface{}{"volume_path": volumePath}}
+
+	components := []interface{}{}
+	for _, component := range currentCatalog().Components {
+		if !component.Custom {
+			continue
+		}
+		spec := component.containers[0]
+		restart := spec.Restart
+		if restart == "" {
+			restart = "unless-stopped"
+		}
+		env := map[string]interface{}{}
+		for name, value := range spec.Env {
+			env[name] = run.expand(value)
+		}
+		components = append(components, map[string]interface{}{
+			"name":    spec.Name,
+			"flag":    component.Flag,
+			"default": component.Default,
+			"image":   spec.Image,
+			"ports":   append([]string{}, spec.Ports...),
+			"env":     env,
+			"volumes": run.expandAll(spec.Volumes),
+			"dirs":    run.expandAll(spec.Dirs),
+			"restart": restart,
+		})
+	}
+	return components
+}
+
+// customComponents stores the user's custom components under the user config directory
+type customComponents struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newCustomComponents creates the custom component store
+func newCustomComponents() *customComponents {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &customComponents{path: filepath.Join(dir, serviceName, "custom-components.json")}
+}
+
+// load reads every custom component
+func (s *customComponents) load() ([]CustomComponent, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []CustomComponent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom components: %v", err)
+	}
+
+	var components []CustomComponent
+	if err := json.Unmarshal(data, &components); err != nil {
+		return nil, fmt.Errorf("failed to parse custom components: %v", err)
+	}
+	return components, nil
+}
+
+// save writes the custom components and makes them part of the current catalog
+func (s *customComponents) save(components []CustomComponent) error {
+	catalog, err := defaultCatalog.withCustom(components)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(components, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal custom components: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write custom components: %v", err)
+	}
+	activeCatalog.Store(&catalog)
+	return nil
+}
+
+// activate adds the saved custom components to the current catalog. If they
+// cannot be loaded the built-in catalog is used on its own.
+func (s *customComponents) activate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	catalog := defaultCatalog
+	components, err := s.load()
+	if err == nil {
+		catalog, err = defaultCatalog.withCustom(components)
+	}
+	if err != nil {
+		log.Printf("Custom components not loaded: %v", err)
+		catalog = defaultCatalog
+	}
+	activeCatalog.Store(&catalog)
+}
+
+// GetCustomComponents returns the user's custom components
+func (a *App) GetCustomComponents() ([]CustomComponent, error) {
+	a.custom.mu.Lock()
+	defer a.custom.mu.Unlock()
+	return a.custom.load()
+}
+
+// AddCustomComponent validates and saves a custom component, replacing any with
+// the same id. It then appears in the catalog's custom stack and is deployed like
+// the built-in components.
+func (a *App) AddCustomComponent(component CustomComponent) error {
+	v := &ValidationError{}
+	component.validate(v)
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	a.custom.mu.Lock()
+	defer a.custom.mu.Unlock()
+
+	components, err := a.custom.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range components {
+		if components[i].ID == component.ID {
+			components[i], replaced = component, true
+		}
+	}
+	if !replaced {
+		components = append(components, component)
+	}
+	return a.custom.save(components)
+}
+
+// RemoveCustomComponent deletes a custom component from the catalog. Undeploy it
+// with RemoveComponents first; containers left on a host are no longer managed.
+func (a *App) RemoveCustomComponent(id string) error {
+	a.custom.mu.Lock()
+	defer a.custom.mu.Unlock()
+
+	components, err := a.custom.load()
+	if err != nil {
+		return err
+	}
+	for i := range components {
+		if components[i].ID == id {
+			return a.custom.save(append(components[:i], components[i+1:]...))
+		}
+	}
+	return fmt.Errorf("custom component %s not found", id)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+// validCustomComponent is a definition that passes validation
+func validCustomComponent() CustomComponent {
+	return CustomComponent{
+		ID:            "sensor-bridge",
+		Name:          "Sensor Bridge",
+		Image:         "registry.local:5000/sensor-bridge:1.2",
+		Ports:         []string{"8085:8080", "5353:53/udp"},
+		Env:           map[string]string{"DATA_DIR": "{data}/sensor-bridge", "TZ": "UTC"},
+		Volumes:       []string{"{data}/sensor-bridge:/data", "/etc/localtime:/etc/localtime:ro"},
+		Restart:       "unless-stopped",
+		HealthURL:     "http://{host}:8085/health",
+		Architectures: []string{"arm64"},
+		RAMMB:         64,
+	}
+}
+
+func TestCustomComponentValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *CustomComponent)
+		field  string
+	}{
+		{"valid", func(c *CustomComponent) {}, ""},
+		{"uppercase id", func(c *CustomComponent) { c.ID = "Sensor" }, "id"},
+		{"double hyphen", func(c *CustomComponent) { c.ID = "sensor--bridge" }, "id"},
+		{"built-in component", func(c *CustomComponent) { c.ID = "grafana" }, "id"},
+		{"built-in container", func(c *CustomComponent) { c.ID = "nextcloud-db" }, "id"},
+		{"missing name", func(c *CustomComponent) { c.Name = " " }, "name"},
+		{"multi-line description", func(c *CustomComponent) { c.Description = "a\nb" }, "description"},
+		{"bad image", func(c *CustomComponent) { c.Image = "Registry/UPPER" }, "image"},
+		{"port without target", func(c *CustomComponent) { c.Ports = []string{"8080"} }, "ports[0]"},
+		{"mixed protocols", func(c *CustomComponent) { c.Ports = []string{"53:53/udp", "5353/udp:53"} }, "ports[1]"},
+		{"duplicate host port", func(c *CustomComponent) { c.Ports = []string{"8080:80", "8080:81"} }, "ports[1]"},
+		{"same port on both protocols", func(c *CustomComponent) { c.Ports = []string{"53:53", "53:53/udp"} }, ""},
+		{"bad env name", func(c *CustomComponent) { c.Env = map[string]string{"1BAD": "x"} }, "env.1BAD"},
+		{"templated env", func(c *CustomComponent) { c.Env = map[string]string{"X": "{{ lookup('pipe', 'id') }}"} }, "env.X"},
+		{"unknown placeholder", func(c *CustomComponent) { c.Env = map[string]string{"X": "{secret:db_password}"} }, "env.X"},
+		{"relative volume", func(c *CustomComponent) { c.Volumes = []string{"data:/data"} }, "volumes[0]"},
+		{"placeholder without slash", func(c *CustomComponent) { c.Volumes = []string{"{data}x:/data"} }, "volumes[0]"},
+		{"volume root", func(c *CustomComponent) { c.Volumes = []string{"/:/host"} }, "volumes[0]"},
+		{"volume traversal", func(c *CustomComponent) { c.Volumes = []string{"{data}/../etc:/etc"} }, "volumes[0]"},
+		{"relative target", func(c *CustomComponent) { c.Volumes = []string{"{data}/a:data"} }, "volumes[0]"},
+		{"bad volume mode", func(c *CustomComponent) { c.Volumes = []string{"{data}/a:/data:z"} }, "volumes[0]"},
+		{"placeholder only", func(c *CustomComponent) { c.Volumes = []string{"{media}:/media"} }, ""},
+		{"bad restart", func(c *CustomComponent) { c.Restart = "sometimes" }, "restart"},
+		{"non-http health", func(c *CustomComponent) { c.HealthURL = "mqtt://{host}:1883" }, "healthUrl"},
+		{"health port out of range", func(c *CustomComponent) { c.HealthURL = "http://{host}:70000/" }, "healthUrl"},
+		{"unknown architecture", func(c *CustomComponent) { c.Architectures = []string{"riscv64"} }, "architectures"},
+		{"negative memory", func(c *CustomComponent) { c.RAMMB = -1 }, "ramMb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component := validCustomComponent()
+			tt.modify(&component)
+
+			v := &ValidationError{}
+			component.validate(v)
+			if tt.field == "" {
+				if err := v.err(); err != nil {
+					t.Fatalf("validate() = %v, want no errors", err)
+				}
+				return
+			}
+			found := false
+			for _, field := range v.Fields {
+				found = found || field.Field == tt.field
+			}
+			if !found {
+				t.Errorf("validate() fields = %+v, want an error on %s", v.Fields, tt.field)
+			}
+		})
+	}
+}
+
+func TestCustomComponentCatalogComponent(t *testing.T) {
+	component := validCustomComponent().catalogComponent()
+
+	if !component.Custom || component.Stack != customStack || component.Flag != "deploy_custom_sensor_bridge" {
+		t.Errorf("catalog component = %+v, want a custom component flagged deploy_custom_sensor_bridge", component)
+	}
+	if len(component.containers) != 1 || component.containers[0].Name != "sensor-bridge" {
+		t.Fatalf("containers = %+v, want one named sensor-bridge", component.containers)
+	}
+	if dirs := component.containers[0].Dirs; len(dirs) != 1 || dirs[0] != "{data}/sensor-bridge" {
+		t.Errorf("dirs = %v, want only the {data} volume created", dirs)
+	}
+}
+
+func TestCatalogWithCustomRejectsClashes(t *testing.T) {
+	clash := validCustomComponent()
+	if _, err := defaultCatalog.withCustom([]CustomComponent{clash, clash}); err == nil {
+		t.Error("withCustom() accepted two components with the same id")
+	}
+
+	merged, err := defaultCatalog.withCustom([]CustomComponent{validCustomComponent()})
+	if err != nil {
+		t.Fatalf("withCustom() error = %v", err)
+	}
+	if len(merged.Components) != len(defaultCatalog.Components)+1 {
+		t.Errorf("merged catalog has %d components, want %d", len(merged.Components), len(defaultCatalog.Components)+1)
+	}
+	if _, ok := defaultCatalog.component(clash.ID); ok {
+		t.Error("withCustom() modified the built-in catalog")
+	}
+}
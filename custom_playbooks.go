@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// customPlaybooksEnv names the environment variable that points at the custom playbook directory
+const customPlaybooksEnv = "DOCKERIZATHINGINATOR_CUSTOM_PLAYBOOKS"
+
+// Variable types a custom playbook manifest may declare
+const (
+	varTypeString = "string"
+	varTypeBool   = "bool"
+	varTypeInt    = "int"
+	varTypeNumber = "number"
+	varTypeList   = "list"
+)
+
+// variableNamePattern matches valid Ansible variable names
+var variableNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PlaybookVariable declares one input of a custom playbook
+type PlaybookVariable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Secret      bool        `json:"secret"`
+	Choices     []string    `json:"choices,omitempty"`
+}
+
+// CustomPlaybook is a user-supplied playbook and its manifest
+type CustomPlaybook struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Variables   []PlaybookVariable `json:"variables"`
+	Path        string             `json:"path"`
+	// Error explains why the playbook cannot be run, e.g. a missing or invalid manifest
+	Error string `json:"error,omitempty"`
+}
+
+// customPlaybooksDir returns the directory searched for custom playbooks
+func customPlaybooksDir() string {
+	if dir := os.Getenv(customPlaybooksEnv); dir != "" {
+		return dir
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, serviceName, "custom-playbooks")
+}
+
+// loadCustomPlaybooks reads every playbook in the custom directory along with its manifest
+func loadCustomPlaybooks() ([]CustomPlaybook, error) {
+	dir := customPlaybooksDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) || dir == "" {
+		return []CustomPlaybook{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom playbooks: %v", err)
+	}
+
+	playbooks := []CustomPlaybook{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yml" {
+			continue
+		}
+		playbooks = append(playbooks, loadCustomPlaybook(dir, entry.Name()))
+	}
+	sort.Slice(playbooks, func(i, j int) bool { return playbooks[i].Name < playbooks[j].Name })
+	return playbooks, nil
+}
+
+// loadCustomPlaybook reads name's manifest, recording any problem in the Error field
+func loadCustomPlaybook(dir, name string) CustomPlaybook {
+	playbook := CustomPlaybook{
+		Name:      name,
+		Title:     strings.TrimSuffix(name, ".yml"),
+		Variables: []PlaybookVariable{},
+		Path:      filepath.Join(dir, name),
+	}
+
+	if err := validatePlaybookName(name); err != nil {
+		playbook.Error = err.Error()
+		return playbook
+	}
+
+	manifestPath := filepath.Join(dir, strings.TrimSuffix(name, ".yml")+".json")
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		playbook.Error = fmt.Sprintf("missing manifest %s", filepath.Base(manifestPath))
+		return playbook
+	}
+
+	var manifest CustomPlaybook
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		playbook.Error = fmt.Sprintf("invalid manifest %s: %v", filepath.Base(manifestPath), err)
+		return playbook
+	}
+
+	if manifest.Title != "" {
+		playbook.Title = manifest.Title
+	}
+	playbook.Description = manifest.Description
+	if manifest.Variables != nil {
+		playbook.Variables = manifest.Variables
+	}
+
+	if err := validateManifestVariables(playbook.Variables); err != nil {
+		playbook.Error = fmt.Sprintf("invalid manifest %s: %v", filepath.Base(manifestPath), err)
+	}
+	return playbook
+}
+
+// validateManifestVariables checks variable declarations, including their defaults
+func validateManifestVariables(variables []PlaybookVariable) error {
+	seen := map[string]bool{}
+	for _, variable := range variables {
+		if !variableNamePattern.MatchString(variable.Name) {
+			return fmt.Errorf("invalid variable name %q", variable.Name)
+		}
+		// Connection and privilege settings come from the app, never from a manifest
+		if strings.HasPrefix(variable.Name, "ansible_") {
+			return fmt.Errorf("variable %s: names starting with ansible_ are reserved", variable.Name)
+		}
+		if seen[variable.Name] {
+			return fmt.Errorf("variable %s is declared twice", variable.Name)
+		}
+		seen[variable.Name] = true
+
+		switch variable.Type {
+		case varTypeString, varTypeBool, varTypeInt, varTypeNumber, varTypeList:
+		default:
+			return fmt.Errorf("variable %s has unknown type %q", variable.Name, variable.Type)
+		}
+		if variable.Default != nil {
+			if _, err := coerceVariable(variable, variable.Default); err != nil {
+				return fmt.Errorf("default for %s %v", variable.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveVariables validates inputs against the manifest and applies defaults,
+// returning the extra-vars and the names of those marked secret
+func (p CustomPlaybook) resolveVariables(inputs map[string]interface{}) (map[string]interface{}, []string, error) {
+	v := &ValidationError{}
+
+	declared := map[string]bool{}
+	for _, variable := range p.Variables {
+		declared[variable.Name] = true
+	}
+	for _, name := range sortedKeys(inputs) {
+		if !declared[name] {
+			v.add(name, "is not declared in the manifest")
+		}
+	}
+
+	vars := map[string]interface{}{}
+	var secrets []string
+	for _, variable := range p.Variables {
+		value, ok := inputs[variable.Name]
+		if !ok || value == nil || value == "" {
+			if variable.Default != nil {
+				value = variable.Default
+			} else if variable.Required {
+				v.add(variable.Name, "is required")
+				continue
+			} else {
+				continue
+			}
+		}
+
+		coerced, err := coerceVariable(variable, value)
+		if err != nil {
+			v.add(variable.Name, "%v", err)
+			continue
+		}
+		vars[variable.Name] = coerced
+		if variable.Secret {
+			secrets = append(secrets, variable.Name)
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, nil, err
+	}
+	return vars, secrets, nil
+}
+
+// coerceVariable converts value to the variable's declared type
+func coerceVariable(variable PlaybookVariable, value interface{}) (interface{}, error) {
+	switch variable.Type {
+	case varTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if len(variable.Choices) > 0 && !containsString(variable.Choices, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(variable.Choices, ", "))
+		}
+		return s, nil
+
+	case varTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("must be true or false")
+
+	case varTypeInt:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a whole number")
+
+	case varTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a number")
+
+	case varTypeList:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("must be a list of strings")
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a list of strings")
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+
+	return nil, fmt.Errorf("has unknown type %q", variable.Type)
+}
+
+// ListCustomPlaybooks returns the playbooks in the custom playbook directory
+func (a *App) ListCustomPlaybooks() ([]CustomPlaybook, error) {
+	return loadCustomPlaybooks()
+}
+
+// RunCustomPlaybook validates inputs against a custom playbook's manifest and runs it
+// on the named backend, or the default one if backend is empty
+func (a *App) RunCustomPlaybook(host, user, password, name, backend string, inputs map[string]interface{}) (RunSummary, error) {
+	if err := validatePlaybookName(name); err != nil {
+		return RunSummary{}, fmt.Errorf("playbook validation failed: %v", err)
+	}
+	if err := validateHostInputs(host, user, nil); err != nil {
+		return RunSummary{}, err
+	}
+
+	playbook := loadCustomPlaybook(customPlaybooksDir(), name)
+	if _, err := os.Stat(playbook.Path); err != nil {
+		return RunSummary{}, fmt.Errorf("custom playbook not found: %s", name)
+	}
+	if playbook.Error != "" {
+		return RunSummary{}, fmt.Errorf("custom playbook %s cannot be run: %s", name, playbook.Error)
+	}
+
+	vars, secrets, err := playbook.resolveVariables(inputs)
+	if err != nil {
+		return RunSummary{}, err
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Running %s...", playbook.Title))
+	return a.runJob(PlaybookJob{
+		Playbook:   name,
+		Host:       host,
+		User:       user,
+		Password:   password,
+		ExtraVars:  vars,
+		SecretVars: secrets,
+		Custom:     true,
+		Backend:    backend,
+	}, "")
+}
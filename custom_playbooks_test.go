@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoerceVariable(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable PlaybookVariable
+		value    interface{}
+		want     interface{}
+		wantErr  bool
+	}{
+		{"string", PlaybookVariable{Type: varTypeString}, "eth0", "eth0", false},
+		{"string from number", PlaybookVariable{Type: varTypeString}, 1.0, nil, true},
+		{"choice", PlaybookVariable{Type: varTypeString, Choices: []string{"a", "b"}}, "b", "b", false},
+		{"not a choice", PlaybookVariable{Type: varTypeString, Choices: []string{"a", "b"}}, "c", nil, true},
+		{"bool", PlaybookVariable{Type: varTypeBool}, true, true, false},
+		{"bool from string", PlaybookVariable{Type: varTypeBool}, "false", false, false},
+		{"bad bool", PlaybookVariable{Type: varTypeBool}, "yes please", nil, true},
+		{"int", PlaybookVariable{Type: varTypeInt}, 42.0, int64(42), false},
+		{"int from string", PlaybookVariable{Type: varTypeInt}, " 7 ", int64(7), false},
+		{"fractional int", PlaybookVariable{Type: varTypeInt}, 1.5, nil, true},
+		{"number", PlaybookVariable{Type: varTypeNumber}, 1.5, 1.5, false},
+		{"number from string", PlaybookVariable{Type: varTypeNumber}, "2.25", 2.25, false},
+		{"bad number", PlaybookVariable{Type: varTypeNumber}, "two", nil, true},
+		{"list", PlaybookVariable{Type: varTypeList}, []interface{}{"a", "b"}, []string{"a", "b"}, false},
+		{"list with a number", PlaybookVariable{Type: varTypeList}, []interface{}{"a", 1.0}, nil, true},
+		{"list from string", PlaybookVariable{Type: varTypeList}, "a,b", nil, true},
+		{"unknown type", PlaybookVariable{Type: "map"}, "x", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coerceVariable(tt.variable, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("coerceVariable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coerceVariable() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	playbook := CustomPlaybook{
+		Name: "wifi.yml",
+		Variables: []PlaybookVariable{
+			{Name: "ssid", Type: varTypeString, Required: true},
+			{Name: "psk", Type: varTypeString, Required: true, Secret: true},
+			{Name: "channel", Type: varTypeInt, Default: 6.0},
+			{Name: "country", Type: varTypeString},
+		},
+	}
+
+	vars, secrets, err := playbook.resolveVariables(map[string]interface{}{"ssid": "home", "psk": "hunter22", "country": ""})
+	if err != nil {
+		t.Fatalf("resolveVariables() error = %v", err)
+	}
+	want := map[string]interface{}{"ssid": "home", "psk": "hunter22", "channel": int64(6)}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+	if !reflect.DeepEqual(secrets, []string{"psk"}) {
+		t.Errorf("secrets = %v, want [psk]", secrets)
+	}
+
+	_, _, err = playbook.resolveVariables(map[string]interface{}{"psk": "x", "channel": "eleven", "extra": true})
+	var fields []string
+	if v, ok := err.(*ValidationError); ok {
+		for _, field := range v.Fields {
+			fields = append(fields, field.Field)
+		}
+	}
+	if want := []string{"extra", "ssid", "channel"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("resolveVariables() errors on %v, want %v", fields, want)
+	}
+}
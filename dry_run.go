@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DryRunResult describes what a playbook run would change on the host
+type DryRunResult struct {
+	Playbook    string       `json:"playbook"`
+	Changed     int          `json:"changed"`
+	Failed      int          `json:"failed"`
+	Unreachable int          `json:"unreachable"`
+	Tasks       []DryRunTask `json:"tasks"`
+}
+
+// DryRunTask is a single task that would change something (or failed to evaluate) in check mode
+type DryRunTask struct {
+	Play    string     `json:"play"`
+	Task    string     `json:"task"`
+	Host    string     `json:"host"`
+	Action  string     `json:"action,omitempty"`
+	Status  string     `json:"status"`
+	Message string     `json:"message,omitempty"`
+	Diffs   []FileDiff `json:"diffs,omitempty"`
+}
+
+// FileDiff is the before/after content Ansible reports for a changed file
+type FileDiff struct {
+	BeforeHeader string `json:"beforeHeader,omitempty"`
+	AfterHeader  string `json:"afterHeader,omitempty"`
+	Before       string `json:"before,omitempty"`
+	After        string `json:"after,omitempty"`
+	Prepared     string `json:"prepared,omitempty"`
+}
+
+// ansibleJSONOutput mirrors the parts of the ansible "json" stdout callback we use
+type ansibleJSONOutput struct {
+	Plays []struct {
+		Play struct {
+			Name string `json:"name"`
+		} `json:"play"`
+		Tasks []struct {
+			Task struct {
+				Name string `json:"name"`
+			} `json:"task"`
+			Hosts map[string]ansibleJSONHostResult `json:"hosts"`
+		} `json:"tasks"`
+	} `json:"plays"`
+}
+
+// ansibleJSONHostResult is the per-host result of a task in the json callback
+type ansibleJSONHostResult struct {
+	Action      string                  `json:"action"`
+	Changed     bool                    `json:"changed"`
+	Failed      bool                    `json:"failed"`
+	Unreachable bool                    `json:"unreachable"`
+	Skipped     bool                    `json:"skipped"`
+	Msg         interface{}             `json:"msg"`
+	Diff        json.RawMessage         `json:"diff"`
+	Results     []ansibleJSONHostResult `json:"results"`
+}
+
+// PreviewPlaybook runs a check-mode command with the json callback and collects the would-change tasks
+func (ar *AnsibleRunner) PreviewPlaybook(ctx context.Context, cmd *exec.Cmd, playbook string) (DryRunResult, error) {
+	cmd.Env = append(cmd.Environ(),
+		"ANSIBLE_STDOUT_CALLBACK=json",
+		"ANSIBLE_NOCOLOR=1",
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return DryRunResult{}, fmt.Errorf("failed to start ansible: %v", err)
+	}
+
+	// Stop the check run when the binding times out or is cancelled
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	// Failed or unreachable hosts exit non-zero but still produce a full report
+	runErr := cmd.Wait()
+	close(done)
+	if ctx.Err() != nil {
+		return DryRunResult{}, ctx.Err()
+	}
+	if stdout.Len() == 0 {
+		if runErr != nil {
+			return DryRunResult{}, fmt.Errorf("ansible check run failed: %v: %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+		return DryRunResult{}, fmt.Errorf("ansible check run produced no output")
+	}
+
+	result, err := parseDryRunOutput(stdout.Bytes())
+	if err != nil {
+		return DryRunResult{}, err
+	}
+	result.Playbook = playbook
+	return result, nil
+}
+
+// parseDryRunOutput converts json callback output into a DryRunResult
+func parseDryRunOutput(data []byte) (DryRunResult, error) {
+	var output ansibleJSONOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return DryRunResult{}, fmt.Errorf("failed to parse ansible check output: %v", err)
+	}
+
+	result := DryRunResult{Tasks: []DryRunTask{}}
+	for _, play := range output.Plays {
+		for _, task := range play.Tasks {
+			for host, hostResult := range task.Hosts {
+				status := hostResult.status()
+				if status == "" {
+					continue
+				}
+
+				result.Tasks = append(result.Tasks, DryRunTask{
+					Play:    play.Play.Name,
+					Task:    task.Task.Name,
+					Host:    host,
+					Action:  hostResult.Action,
+					Status:  status,
+					Message: hostResult.message(),
+					Diffs:   hostResult.diffs(),
+				})
+
+				switch status {
+				case "changed":
+					result.Changed++
+				case "failed":
+					result.Failed++
+				case "unreachable":
+					result.Unreachable++
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// status reports the interesting outcome of a host result, or "" for ok/skipped
+func (r ansibleJSONHostResult) status() string {
+	if r.Unreachable {
+		return "unreachable"
+	}
+	if r.Failed {
+		return "failed"
+	}
+	if r.Changed {
+		return "changed"
+	}
+	for _, item := range r.Results {
+		if s := item.status(); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// message flattens the msg field, which Ansible emits as a string or a list
+func (r ansibleJSONHostResult) message() string {
+	switch msg := r.Msg.(type) {
+	case nil:
+		return ""
+	case string:
+		return msg
+	default:
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Sprint(msg)
+		}
+		return string(encoded)
+	}
+}
+
+// diffs collects file diffs from the result and any loop items
+func (r ansibleJSONHostResult) diffs() []FileDiff {
+	diffs := decodeAnsibleDiff(r.Diff)
+	for _, item := range r.Results {
+		if item.Changed {
+			diffs = append(diffs, decodeAnsibleDiff(item.Diff)...)
+		}
+	}
+	return diffs
+}
+
+// decodeAnsibleDiff handles the single-object and list forms of the diff field
+func decodeAnsibleDiff(raw json.RawMessage) []FileDiff {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	type rawDiff struct {
+		BeforeHeader string      `json:"before_header"`
+		AfterHeader  string      `json:"after_header"`
+		Before       interface{} `json:"before"`
+		After        interface{} `json:"after"`
+		Prepared     string      `json:"prepared"`
+	}
+
+	var list []rawDiff
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single rawDiff
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil
+		}
+		list = []rawDiff{single}
+	}
+
+	var diffs []FileDiff
+	for _, d := range list {
+		diff := FileDiff{
+			BeforeHeader: d.BeforeHeader,
+			AfterHeader:  d.AfterHeader,
+			Before:       diffText(d.Before),
+			After:        diffText(d.After),
+			Prepared:     d.Prepared,
+		}
+		if diff != (FileDiff{}) {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// diffText renders a before/after value, which modules report as text or structured data
+func diffText(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		encoded, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(encoded)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const dryRunOutput = `{
+  "plays": [{
+    "play": {"name": "Configure storage"},
+    "tasks": [
+      {"task": {"name": "List available USB devices"}, "hosts": {"pi": {"action": "shell", "changed": false}}},
+      {"task": {"name": "Wipe filesystem signatures"}, "hosts": {"pi": {"action": "command", "changed": true}}},
+      {"task": {"name": "Add fstab entry"}, "hosts": {"pi": {
+        "action": "mount", "changed": true,
+        "diff": [{"before_header": "/etc/fstab (content)", "after_header": "/etc/fstab (content)", "before": "a\n", "after": "a\nb\n"}]
+      }}},
+      {"task": {"name": "Write daemon.json"}, "hosts": {"pi": {
+        "action": "copy", "changed": true,
+        "diff": {"after_header": "/etc/docker/daemon.json", "prepared": "+{\"log-driver\": \"json-file\"}"}
+      }}},
+      {"task": {"name": "Create directories"}, "hosts": {"pi": {
+        "action": "file",
+        "results": [{"changed": false}, {"changed": true, "diff": {"after_header": "/mnt/docker/data"}}]
+      }}},
+      {"task": {"name": "Skipped in check mode"}, "hosts": {"pi": {"action": "command", "skipped": true}}},
+      {"task": {"name": "Fail if device is not removable"}, "hosts": {"pi": {"action": "fail", "failed": true, "msg": ["not", "removable"]}}}
+    ]
+  }]
+}`
+
+func TestParseDryRunOutput(t *testing.T) {
+	result, err := parseDryRunOutput([]byte(dryRunOutput))
+	if err != nil {
+		t.Fatalf("parseDryRunOutput() error = %v", err)
+	}
+
+	if result.Changed != 4 || result.Failed != 1 || result.Unreachable != 0 {
+		t.Errorf("counts = changed %d, failed %d, unreachable %d; want 4, 1, 0", result.Changed, result.Failed, result.Unreachable)
+	}
+
+	var tasks []string
+	for _, task := range result.Tasks {
+		tasks = append(tasks, task.Task)
+	}
+	want := []string{"Wipe filesystem signatures", "Add fstab entry", "Write daemon.json", "Create directories", "Fail if device is not removable"}
+	if !reflect.DeepEqual(tasks, want) {
+		t.Errorf("tasks = %v, want %v", tasks, want)
+	}
+
+	fstab := result.Tasks[1].Diffs
+	if len(fstab) != 1 || fstab[0].Before != "a\n" || fstab[0].After != "a\nb\n" {
+		t.Errorf("fstab diffs = %+v, want the before and after content", fstab)
+	}
+	if daemon := result.Tasks[2].Diffs; len(daemon) != 1 || daemon[0].Prepared == "" {
+		t.Errorf("daemon.json diffs = %+v, want a single prepared diff", daemon)
+	}
+	if loop := result.Tasks[3].Diffs; len(loop) != 1 || loop[0].AfterHeader != "/mnt/docker/data" {
+		t.Errorf("loop diffs = %+v, want only the changed item", loop)
+	}
+	if failed := result.Tasks[4]; failed.Status != "failed" || failed.Message != `["not","removable"]` {
+		t.Errorf("failed task = %+v, want status failed with the list message", failed)
+	}
+}
+
+func TestParseDryRunOutputUnreachable(t *testing.T) {
+	output := `{"plays": [{"play": {"name": "p"}, "tasks": [{"task": {"name": "Gathering Facts"}, "hosts": {"pi": {"unreachable": true, "msg": "timed out"}}}]}]}`
+	result, err := parseDryRunOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("parseDryRunOutput() error = %v", err)
+	}
+	if result.Unreachable != 1 || result.Tasks[0].Status != "unreachable" || result.Tasks[0].Message != "timed out" {
+		t.Errorf("result = %+v, want one unreachable task", result)
+	}
+}
+
+func TestParseDryRunOutputInvalid(t *testing.T) {
+	if _, err := parseDryRunOutput([]byte("ERROR! the playbook could not be found")); err == nil {
+		t.Error("parseDryRunOutput() accepted non-JSON output")
+	}
+}
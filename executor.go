@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backendAnsible = "ansible"
+	backendNative  = "native"
+)
+
+// PlaybookJob describes a single deployment run, independent of how it is executed
+type PlaybookJob struct {
+	Playbook  string
+	Host      string
+	User      string
+	Password  string
+	ExtraVars map[string]interface{}
+	Options   RunOptions
+	// SecretVars names extra-vars to treat as secret in addition to those matched by name
+	SecretVars []string
+	// Custom marks a playbook from the user's custom playbook directory
+	Custom bool
+	// Policy sets the timeouts and retries for the run
+	Policy OperationsPolicy
+	// Compose is the docker compose action of a compose deployment, which always
+	// runs over SSH whichever backend is selected
+	Compose string
+	// Backend names the backend the job runs on; empty selects the default
+	Backend string
+}
+
+// isSecretVar reports whether the whole value of the named extra-var is secret
+func (job PlaybookJob) isSecretVar(key string) bool {
+	return secretVarPattern.MatchString(key) || containsString(job.SecretVars, key)
+}
+
+// splitVars separates the job's secret extra-vars from the ones safe to pass as
+// arguments. A var with a secret nested inside it, such as an env map holding a
+// password, goes to the secret set whole.
+func (job PlaybookJob) splitVars() (plain, secret map[string]interface{}) {
+	plain = map[string]interface{}{}
+	secret = map[string]interface{}{}
+	for key, value := range job.ExtraVars {
+		if job.isSecretVar(key) || hasNestedSecret(value) {
+			secret[key] = value
+		} else {
+			plain[key] = value
+		}
+	}
+	return plain, secret
+}
+
+// RunOptions narrow a run to part of a playbook or a subset of hosts
+type RunOptions struct {
+	Tags        []string `json:"tags,omitempty"`
+	SkipTags    []string `json:"skipTags,omitempty"`
+	StartAtTask string   `json:"startAtTask,omitempty"`
+	Limit       []string `json:"limit,omitempty"`
+}
+
+// validate rejects values that ansible-playbook would split or misparse
+func (o RunOptions) validate() error {
+	for _, list := range [][]string{o.Tags, o.SkipTags, o.Limit} {
+		for _, value := range list {
+			if strings.TrimSpace(value) == "" || strings.ContainsAny(value, ",\n\r") {
+				return fmt.Errorf("invalid tag or host name: %q", value)
+			}
+		}
+	}
+	if strings.ContainsAny(o.StartAtTask, "\n\r") {
+		return fmt.Errorf("invalid task name: %q", o.StartAtTask)
+	}
+	return nil
+}
+
+// args converts the options into ansible-playbook arguments. The --flag=value form
+// keeps values starting with a dash from being read as flags.
+func (o RunOptions) args() []string {
+	var args []string
+	if len(o.Tags) > 0 {
+		args = append(args, "--tags="+strings.Join(o.Tags, ","))
+	}
+	if len(o.SkipTags) > 0 {
+		args = append(args, "--skip-tags="+strings.Join(o.SkipTags, ","))
+	}
+	if o.StartAtTask != "" {
+		args = append(args, "--start-at-task="+o.StartAtTask)
+	}
+	if len(o.Limit) > 0 {
+		args = append(args, "--limit="+strings.Join(o.Limit, ","))
+	}
+	return args
+}
+
+// ExecutionBackend runs playbook jobs against a host and reports progress
+// through the same ansibleOutput/ansibleComplete/ansibleError events
+type ExecutionBackend interface {
+	Name() string
+	Description() string
+	Available() bool
+	Run(ctx context.Context, job PlaybookJob) error
+}
+
+// ExecutionBackendInfo describes a backend to the frontend
+type ExecutionBackendInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Available   bool   `json:"available"`
+	Default     bool   `json:"default"`
+}
+
+// AnsibleBackend runs jobs with the local ansible-playbook binary
+type AnsibleBackend struct {
+	runner *AnsibleRunner
+}
+
+// NewAnsibleBackend creates a new AnsibleBackend
+func NewAnsibleBackend(runner *AnsibleRunner) *AnsibleBackend {
+	return &AnsibleBackend{runner: runner}
+}
+
+// Name returns the backend identifier
+func (b *AnsibleBackend) Name() string {
+	return backendAnsible
+}
+
+// Description returns a human readable summary of the backend
+func (b *AnsibleBackend) Description() string {
+	return "Runs the bundled Ansible playbooks with ansible-playbook on this computer, or in a local Docker/Podman container if Ansible is not installed"
+}
+
+// Available reports whether ansible-playbook can be found locally or run in a container
+func (b *AnsibleBackend) Available() bool {
+	_, err := resolveAnsibleLauncher()
+	return err == nil
+}
+
+// Run executes the job's playbook with ansible-playbook. Failures the job's policy
+// recognises as transient are retried after a backoff. A retry runs the playbook
+// from the start again, since facts and registered results from tasks before the
+// failing one would be missing if it started at that task.
+func (b *AnsibleBackend) Run(ctx context.Context, job PlaybookJob) error {
+	tracker := runTrackerFrom(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := b.attempt(ctx, job, job.Options)
+		if _, ok := err.(*commandBuildError); ok {
+			return err
+		}
+		if ctx.Err() != nil {
+			return b.runner.finishRun(ctx, runContextError(ctx, job.Policy))
+		}
+		if err == nil || tracker == nil || attempt > job.Policy.Retries {
+			return b.runner.finishRun(ctx, err)
+		}
+
+		task, reason := tracker.transientFailure()
+		if reason == "" {
+			return b.runner.finishRun(ctx, err)
+		}
+		if job.Policy.waitToRetry(ctx, attempt, task, reason) != nil {
+			return b.runner.finishRun(ctx, runContextError(ctx, job.Policy))
+		}
+
+		tracker.reset()
+	}
+}
+
+// commandBuildError reports that ansible-playbook could not be prepared, so nothing ran
+type commandBuildError struct {
+	err error
+}
+
+func (e *commandBuildError) Error() string {
+	return e.err.Error()
+}
+
+// attempt runs ansible-playbook once with the given options
+func (b *AnsibleBackend) attempt(ctx context.Context, job PlaybookJob, options RunOptions) error {
+	args := append(options.args(), fmt.Sprintf("--timeout=%d", int(job.Policy.connectTimeout()/time.Second)))
+	cmd, cleanup, err := buildPlaybookCommand(job, args...)
+	if err != nil {
+		return &commandBuildError{err: err}
+	}
+	defer cleanup()
+
+	// Stream output to frontend
+	return b.runner.runCommand(ctx, cmd)
+}
+
+// registerBackends sets up the available execution backends
+func (a *App) registerBackends() {
+	a.backends = map[string]ExecutionBackend{}
+	for _, backend := range []ExecutionBackend{
+		NewAnsibleBackend(a.ansibleRunner),
+		NewNativeBackend(a.ansibleRunner),
+	} {
+		a.backends[backend.Name()] = backend
+	}
+}
+
+// defaultBackend is the backend used when a run does not choose one: Ansible,
+// falling back to the native executor when it is missing
+func (a *App) defaultBackend() string {
+	if a.backends[backendAnsible].Available() {
+		return backendAnsible
+	}
+	return backendNative
+}
+
+// backendFor returns the backend a job runs on. Compose deployments and upgrades
+// always run over SSH, whichever backend the job asks for.
+func (a *App) backendFor(job PlaybookJob) (ExecutionBackend, error) {
+	name := job.Backend
+	switch {
+	case job.Compose != "" || job.Playbook == upgradePlaybook:
+		name = backendNative
+	case name == "":
+		name = a.defaultBackend()
+	}
+
+	backend, ok := a.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown execution backend: %s", name)
+	}
+	if !backend.Available() {
+		return nil, fmt.Errorf("execution backend %s is not available on this system", name)
+	}
+	return backend, nil
+}
+
+// GetExecutionBackends lists the execution backends and which one runs jobs that
+// do not choose one
+func (a *App) GetExecutionBackends() []ExecutionBackendInfo {
+	defaultName := a.defaultBackend()
+	infos := make([]ExecutionBackendInfo, 0, len(a.backends))
+	for name, backend := range a.backends {
+		infos = append(infos, ExecutionBackendInfo{
+			Name:        name,
+			Description: backend.Description(),
+			Available:   backend.Available(),
+			Default:     name == defaultName,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// deploymentBundle collects the files of an exported deployment
+type deploymentBundle struct {
+	tar     *tar.Writer
+	modTime time.Time
+}
+
+// add writes one file into the bundle
+func (b *deploymentBundle) add(name string, data []byte, mode int64) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %v", name, err)
+	}
+	if _, err := b.tar.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %v", name, err)
+	}
+	return nil
+}
+
+// addTree writes every file under dir into the bundle beneath prefix
+func (b *deploymentBundle) addTree(dir, prefix string) error {
+	return filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		return b.add(path.Join(prefix, filepath.ToSlash(rel)), data, 0644)
+	})
+}
+
+// specSecrets returns the names of the secrets a container's spec refers to
+func specSecrets(spec containerSpec) []string {
+	text := append([]string{}, spec.Command...)
+	text = append(text, spec.PostCommands...)
+	for _, value := range spec.Env {
+		text = append(text, value)
+	}
+	for _, file := range spec.Files {
+		text = append(text, file.Content)
+	}
+	if spec.Credentials != nil {
+		text = append(text, spec.Credentials.Content)
+	}
+
+	var names []string
+	for _, s := range text {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+			if name := strings.TrimPrefix(match[1], "secret:"); name != match[1] && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// writeDeploymentBundle writes a gzipped tar of everything needed to apply a
+// deployment by hand. Secrets are generated, or left as CHANGE_ME placeholders.
+func writeDeploymentBundle(w io.Writer, host, user, volumePath string, config StackConfig, generateSecrets bool) error {
+	vars := stackVars(volumePath, config)
+	run := &nativeRun{
+		job:     PlaybookJob{Host: host, User: user},
+		vars:    vars,
+		secrets: map[string]string{},
+	}
+	stacks, containers := run.composeStacks()
+
+	if !generateSecrets {
+		for _, stack := range stacks {
+			for _, spec := range containers[stack] {
+				for _, name := range specSecrets(spec) {
+					run.secrets[name] = "CHANGE_ME_" + composeVarName(name)
+				}
+			}
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	bundle := &deploymentBundle{tar: tar.NewWriter(gz), modTime: time.Now()}
+
+	if err := bundle.writeContents(run, stacks, containers); err != nil {
+		return err
+	}
+	if err := bundle.tar.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle: %v", err)
+	}
+	return gz.Close()
+}
+
+// writeContents adds the compose projects, config files, playbooks and README
+func (b *deploymentBundle) writeContents(run *nativeRun, stacks []string, containers map[string][]containerSpec) error {
+	// Compose projects, one directory per stack
+	for _, stack := range stacks {
+		project := renderComposeProject(stack, run.composeDir(stack), containers[stack], run.expand, run.secret)
+		if err := b.add(path.Join("compose", stack, "docker-compose.yml"), []byte(project.Compose), 0644); err != nil {
+			return err
+		}
+		if err := b.add(path.Join("compose", stack, ".env"), []byte(project.Env), 0600); err != nil {
+			return err
+		}
+	}
+
+	// Configuration files the containers expect, at their path on the host
+	for _, stack := range stacks {
+		for _, spec := range containers[stack] {
+			for _, file := range spec.Files {
+				if file.When != "" && !run.boolVar(file.When, flagDefault(file.When)) {
+					continue
+				}
+				file := run.expandFile(file)
+				if err := b.add(path.Join("files", file.Path), []byte(file.Content), 0600); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// The exact playbooks, overrides included, with the extra-vars and inventory they would get
+	workspace, err := NewPlaybookWorkspace()
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+	if err := b.addTree(workspace.AnsibleDir(), "ansible"); err != nil {
+		return err
+	}
+
+	extraVars, err := json.MarshalIndent(run.vars, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal extra-vars: %v", err)
+	}
+	if err := b.add("extra-vars.json", append(extraVars, '\n'), 0644); err != nil {
+		return err
+	}
+	if err := b.add("inventory.yml", buildInventory(run.job.Host, run.job.User, false), 0644); err != nil {
+		return err
+	}
+
+	return b.add("README.md", []byte(bundleReadme(run, stacks, containers)), 0644)
+}
+
+// bundleReadme explains the bundle and lists every service with its ports and URL
+func bundleReadme(run *nativeRun, stacks []string, containers map[string][]containerSpec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Dockerizathinginator deployment for %s\n\n", run.job.Host)
+	fmt.Fprintf(&b, "Exported %s by Dockerizathinginator %s.\n\n", time.Now().Format("2006-01-02 15:04"), playbookVersion())
+
+	b.WriteString("## Services\n\n")
+	b.WriteString("| Service | Stack | Image | Ports | URL |\n|---|---|---|---|---|\n")
+	specs := map[string]containerSpec{}
+	for _, stack := range stacks {
+		for _, spec := range containers[stack] {
+			specs[spec.Name] = spec
+		}
+	}
+	for _, component := range currentCatalog().Components {
+		if !containsString(stacks, component.Stack) || !run.boolVar(component.Flag, component.Default) {
+			continue
+		}
+		var published []string
+		for _, spec := range component.containers {
+			published = append(published, specs[spec.Name].Ports...)
+		}
+		ports := strings.Join(published, ", ")
+		if ports == "" {
+			ports = "host network"
+		}
+		fmt.Fprintf(&b, "| %s | %s | `%s` | %s | %s |\n", component.Name, component.Stack, component.Image, ports, run.expand(component.URL))
+	}
+
+	b.WriteString("\n## Applying with Docker Compose\n\n")
+	b.WriteString("1. Copy everything under `files/` to the same paths on the Pi, keeping any files that already exist.\n")
+	b.WriteString("2. Create the shared network once: `docker network create docker_network`.\n")
+	fmt.Fprintf(&b, "3. Copy `compose/` to `%s` and start each stack:\n\n", path.Dir(run.composeDir("stack")))
+	for _, stack := range stacks {
+		fmt.Fprintf(&b, "       cd %s && docker compose up -d\n", run.composeDir(stack))
+	}
+
+	var postCommands []string
+	for _, stack := range stacks {
+		for _, spec := range containers[stack] {
+			for _, command := range spec.PostCommands {
+				postCommands = append(postCommands, run.expand(command))
+			}
+		}
+	}
+	if len(postCommands) > 0 {
+		b.WriteString("\n4. Finish setup with:\n\n")
+		for _, command := range postCommands {
+			fmt.Fprintf(&b, "       %s\n", command)
+		}
+	}
+
+	b.WriteString("\n## Applying with Ansible\n\n")
+	b.WriteString("    ansible-playbook -i inventory.yml ansible/playbooks/main.yml -e @extra-vars.json --ask-pass --ask-become-pass\n")
+
+	b.WriteString("\n## Secrets\n\n")
+	b.WriteString("Passwords are kept in each stack's `.env` file and in the files under `files/`. ")
+	b.WriteString("Replace any `CHANGE_ME_` values before applying, and keep this bundle private if it holds generated passwords.\n")
+	return b.String()
+}
+
+// ExportDeployment saves the selected deployment as an offline bundle: compose files,
+// .env files, the playbooks and extra-vars that would be used, and a README of ports
+// and URLs. It returns the saved path, or "" if the user cancelled.
+func (a *App) ExportDeployment(host, user, volumePath string, config StackConfig, generateSecrets bool) (string, error) {
+	if err := validateStackInputs(host, user, volumePath, config); err != nil {
+		return "", err
+	}
+	plan, err := resolveDeployment(config, 0, "")
+	if err != nil {
+		return "", err
+	}
+
+	file, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export deployment",
+		DefaultFilename: fmt.Sprintf("dockerizathinginator-%s-%s.tar.gz", strings.ReplaceAll(host, ":", "_"), time.Now().Format("20060102")),
+		Filters:         []runtime.FileFilter{{DisplayName: "Deployment bundle (*.tar.gz)", Pattern: "*.tar.gz"}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to choose export location: %v", err)
+	}
+	if file == "" {
+		return "", nil
+	}
+
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle: %v", err)
+	}
+	if err := writeDeploymentBundle(out, host, user, volumePath, plan.Config, generateSecrets); err != nil {
+		out.Close()
+		os.Remove(file)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write bundle: %v", err)
+	}
+	return file, nil
+}
@@ -12,12 +12,16 @@ let user = 'pi';
 let piPass = '';
 let vol = '/mnt/docker';
 let netType = 'usb';
+let usbDevice = 'sdb';
 let back = 'none';
 let log = 'Log2Ram';
 let stack = 'Network Stack';
+let catalog = null;
+let componentOverrides = {};
 let netShare = '';
 let netUser = '';
 let netPassword = '';
+let backend = '';
 let containers = [];
 let ghUser = 'None';
 let ghEmail = 'None';
@@ -49,6 +53,12 @@ function initializeApp() {
 
     // Listen for Wails events
     listenForWailsEvents();
+
+    // Build the stack selection from the catalog
+    loadCatalog();
+
+    // Offer the execution backends, selecting the default one
+    loadExecutionBackends();
 }
 
 function hideElements(selectors) {
@@ -114,6 +124,11 @@ function bindEventHandlers() {
 
     // Connection test button
     document.getElementById('connectTest').addEventListener('click', testConnection);
+    document.getElementById('exportButton')?.addEventListener('click', exportDeployment);
+    document.getElementById('checkUpdatesButton')?.addEventListener('click', checkImageUpdates);
+    document.getElementById('upgradeButton')?.addEventListener('click', upgradeSelectedComponents);
+    document.getElementById('removeButton')?.addEventListener('click', removeSelectedComponents);
+    document.getElementById('addCustomButton')?.addEventListener('click', addCustomComponent);
 
     // Form input handlers
     document.getElementById('piHost').addEventListener('input', (e) => { host = e.target.value; });
@@ -289,6 +304,20 @@ function showAlert(type, message) {
     }, 3000);
 }
 
+// Summarise PLAY RECAP counters for display, e.g. " (pi: 12 ok, 3 changed)"
+function formatRunSummary(summary) {
+    if (!summary || !summary.hosts || summary.hosts.length === 0) return '';
+
+    const hosts = summary.hosts.map(h => {
+        const parts = [`${h.ok} ok`, `${h.changed} changed`];
+        if (h.skipped) parts.push(`${h.skipped} skipped`);
+        if (h.failed) parts.push(`${h.failed} failed`);
+        if (h.unreachable) parts.push(`${h.unreachable} unreachable`);
+        return `${h.host}: ${parts.join(', ')}`;
+    });
+    return ` (${hosts.join('; ')})`;
+}
+
 // Listen for Wails events
 function listenForWailsEvents() {
     if (!ensureWails()) return;
@@ -299,17 +328,36 @@ function listenForWailsEvents() {
         updateProgressDisplay(message);
     });
 
-    // Listen for Ansible output
-    window.runtime.EventsOn('ansibleOutput', (data) => {
-        console.log('Ansible output:', data);
-        appendAnsibleOutput(data.message);
+    // Listen for Ansible output, delivered in batches of sequenced lines
+    window.runtime.EventsOn('ansibleOutput', (lines) => {
+        appendAnsibleOutput(lines);
+    });
+
+    // Listen for retries of steps that failed for a transient reason
+    window.runtime.EventsOn('runRetry', (retry) => {
+        console.log('Retrying:', retry);
+        const step = retry.task ? ` after "${retry.task}" failed` : '';
+        appendAnsibleOutput([{
+            type: 'stderr',
+            message: `🔁 Retrying${step} in ${retry.delaySeconds}s (attempt ${retry.attempt} of ${retry.maxAttempts}): ${retry.reason}`,
+        }]);
+    });
+
+    // Listen for the stages of component upgrades
+    window.runtime.EventsOn('upgradeProgress', (progress) => {
+        console.log('Upgrade:', progress);
+        updateProgressDisplay(progress.message);
+        appendAnsibleOutput([{
+            type: progress.stage === 'rollback' ? 'stderr' : 'stdout',
+            message: `⬆️ ${progress.message}`,
+        }]);
     });
 
     // Listen for Ansible completion
-    window.runtime.EventsOn('ansibleComplete', (message) => {
-        console.log('Ansible complete:', message);
+    window.runtime.EventsOn('ansibleComplete', (result) => {
+        console.log('Ansible complete:', result);
         hideAnsibleModal();
-        showAlert('success', 'Deployment completed successfully!');
+        showAlert('success', `Deployment completed successfully!${formatRunSummary(result.summary)}`);
     });
 
     // Listen for Ansible errors
@@ -369,14 +417,20 @@ function hideAnsibleModal() {
     }
 }
 
-function appendAnsibleOutput(message) {
+function appendAnsibleOutput(lines) {
     const output = document.getElementById('ansibleOutput');
-    if (output && message) {
+    if (!output || !lines || lines.length === 0) return;
+
+    // Build the batch off-screen so the webview lays out once per batch, not per line
+    const fragment = document.createDocumentFragment();
+    lines.forEach(({ message, type }) => {
         const line = document.createElement('div');
         line.textContent = message;
-        output.appendChild(line);
-        output.scrollTop = output.scrollHeight;
-    }
+        if (type === 'stderr') line.classList.add('text-yellow-400');
+        fragment.appendChild(line);
+    });
+    output.appendChild(fragment);
+    output.scrollTop = output.scrollHeight;
 }
 
 function updateStatusDisplay(status) {
@@ -384,6 +438,182 @@ function updateStatusDisplay(status) {
     console.log('Status:', status.status, 'Success:', status.success);
 }
 
+// Stack and component catalog
+async function loadCatalog() {
+    if (!ensureWails()) return;
+
+    try {
+        catalog = await window.go.main.App.GetCatalog();
+        renderCatalog();
+    } catch (error) {
+        console.error('Failed to load catalog:', error);
+    }
+}
+
+async function loadExecutionBackends() {
+    if (!ensureWails()) return;
+
+    try {
+        const backends = await window.go.main.App.GetExecutionBackends();
+        const select = document.getElementById('executionBackend');
+        if (!select) return;
+        select.innerHTML = '';
+        backends.forEach(info => {
+            const option = document.createElement('option');
+            option.value = info.name;
+            option.textContent = info.available ? `${info.name} – ${info.description}` : `${info.name} (not available)`;
+            option.disabled = !info.available;
+            option.selected = info.default;
+            select.appendChild(option);
+            if (info.default) backend = info.name;
+        });
+        select.addEventListener('change', () => { backend = select.value; });
+    } catch (error) {
+        console.error('Failed to load execution backends:', error);
+    }
+}
+
+function renderCatalog() {
+    const container = document.getElementById('stackCatalog');
+    if (!container || !catalog) return;
+    container.innerHTML = '';
+
+    catalog.stacks.forEach(stackInfo => {
+        const components = catalog.components.filter(c => c.stack === stackInfo.id);
+        if (components.length === 0) return;
+
+        const card = document.createElement('div');
+        card.className = 'glass-card p-4';
+
+        const title = document.createElement('h2');
+        title.className = 'text-lg font-semibold text-ctp-text mb-1';
+        title.textContent = stackInfo.name;
+        card.appendChild(title);
+
+        const description = document.createElement('p');
+        description.className = 'text-ctp-subtext1 mb-3 text-sm';
+        description.textContent = stackInfo.description;
+        card.appendChild(description);
+
+        // Stacks without a flag are always deployed; their components are toggled individually
+        if (stackInfo.flag) {
+            card.appendChild(catalogCheckbox(`stack-${stackInfo.id}`, `Select ${stackInfo.name}`, false, 'font-medium'));
+        }
+
+        const list = document.createElement('div');
+        list.className = 'ml-6 mt-2 grid grid-cols-1 gap-1';
+        components.forEach(component => {
+            const label = `${component.name} — ${component.description} (~${component.ramMb} MB RAM)`;
+            list.appendChild(catalogCheckbox(`component-${component.flag}`, label, component.default, 'text-sm'));
+        });
+        card.appendChild(list);
+
+        container.appendChild(card);
+    });
+}
+
+function catalogCheckbox(id, text, checked, labelClass) {
+    const row = document.createElement('div');
+    row.className = 'flex items-center';
+
+    const input = document.createElement('input');
+    input.type = 'checkbox';
+    input.id = id;
+    input.checked = checked;
+    input.className = 'w-4 h-4 text-ctp-mauve bg-transparent border-2 border-ctp-overlay0 rounded';
+    row.appendChild(input);
+
+    const label = document.createElement('label');
+    label.htmlFor = id;
+    label.className = `ml-3 text-ctp-text cursor-pointer ${labelClass}`;
+    label.textContent = text;
+    row.appendChild(label);
+
+    return row;
+}
+
+// Build a StackConfig from the catalog selections
+function selectedStackConfig() {
+    const config = { stacks: {}, components: {}, overrides: componentOverrides };
+    if (!catalog) return config;
+
+    catalog.stacks.forEach(stackInfo => {
+        if (stackInfo.flag) {
+            config.stacks[stackInfo.id] = !!document.getElementById(`stack-${stackInfo.id}`)?.checked;
+        }
+    });
+    catalog.components.forEach(component => {
+        const input = document.getElementById(`component-${component.flag}`);
+        config.components[component.flag] = input ? input.checked : component.default;
+    });
+    return config;
+}
+
+// Describe a deployment plan for confirmation before it runs
+function formatDeploymentPlan(plan) {
+    const lines = ['The following components will be deployed in this order:', ''];
+    plan.steps.forEach((step, index) => {
+        const reason = step.reason === 'selected' ? '' : ` (${step.reason})`;
+        lines.push(`${index + 1}. ${step.name}${reason}`);
+    });
+    if (plan.hostRamMb) {
+        lines.push('', `Estimated memory: ${plan.ramMb}MB of ${plan.hostRamMb}MB`);
+    }
+    if (plan.hostArch) {
+        lines.push(`Host architecture: ${plan.hostArch}`);
+    }
+    [...plan.included, ...plan.warnings].forEach(message => lines.push('', message));
+    lines.push('', 'Continue?');
+    return lines.join('\n');
+}
+
+// Describe port collisions and the final port map for confirmation
+function formatPortPlan(ports) {
+    const lines = ['Some host ports are already taken:', ''];
+    ports.conflicts.forEach(conflict => {
+        lines.push(`${conflict.port}/${conflict.protocol} (${conflict.holders.join(', ')}): ${conflict.resolution}`);
+    });
+    lines.push('', 'Final port map:');
+    ports.bindings.forEach(binding => {
+        lines.push(`${binding.container}: ${binding.hostPort}/${binding.protocol} -> ${binding.target}`);
+    });
+    lines.push('', 'Deploy with these ports?');
+    return lines.join('\n');
+}
+
+// Tick the components a plan added so the selection matches what is deployed
+function applyPlanSelection(plan) {
+    Object.entries(plan.config.components).forEach(([flag, enabled]) => {
+        const input = document.getElementById(`component-${flag}`);
+        if (input && enabled) input.checked = true;
+    });
+}
+
+// Describe what a check-mode preview would change for approval
+function formatDryRun(result) {
+    const lines = [`${result.playbook}: ${result.changed} change(s), ${result.failed} failure(s)`, ''];
+    result.tasks.forEach(task => {
+        lines.push(`[${task.status}] ${task.task}${task.message ? `: ${task.message}` : ''}`);
+        (task.diffs || []).forEach(diff => {
+            if (diff.afterHeader) lines.push(`  ${diff.afterHeader}`);
+            if (diff.prepared) lines.push(diff.prepared.split('\n').map(line => `    ${line}`).join('\n'));
+        });
+    });
+    if (result.tasks.length === 0) lines.push('Nothing would change.');
+    if (result.unreachable > 0) lines.push('', 'The host could not be reached.');
+    lines.push('', 'Apply these changes?');
+    return lines.join('\n');
+}
+
+// Preview a run in check mode and ask for approval before it runs
+async function approvePreview(label, preview) {
+    try {
+        return confirm(formatDryRun(await preview()));
+    } catch (error) {
+        return confirm(`${label} could not be previewed: ${error.message || error}\n\nRun it without a preview?`);
+    }
+}
+
 // Deployment functions (converted from original Eel functions)
 async function deployComplete() {
     if (!ensureWails() || !connection) {
@@ -392,23 +622,179 @@ async function deployComplete() {
     }
 
     try {
-        showAnsibleModal();
-        
-        const config = {
-            networkStack: true, // Based on your original default
-            iotStack: false,
-            mediaStack: false,
-            components: {
-                deploy_pihole: true,
-                deploy_portainer: true,
+        const plan = await window.go.main.App.PlanDeployment(host, user, piPass, vol, selectedStackConfig());
+        if (!confirm(formatDeploymentPlan(plan))) return;
+        applyPlanSelection(plan);
+
+        const ports = await window.go.main.App.PlanPorts(host, user, piPass, vol, selectedStackConfig());
+        if (ports.conflicts.length > 0) {
+            const unresolved = ports.conflicts.filter(conflict => !conflict.resolved);
+            if (unresolved.length > 0) {
+                showAlert('error', `Port conflicts: ${unresolved.map(conflict => conflict.resolution).join('; ')}`);
+                return;
             }
-        };
+            if (!confirm(formatPortPlan(ports))) return;
+            componentOverrides = ports.config.overrides || {};
+        }
 
-        await window.go.main.App.DeployStacks(host, user, piPass, vol, config);
+        const config = selectedStackConfig();
+        if (!await approvePreview('The deployment', () => window.go.main.App.PreviewDeployStacks(host, user, piPass, vol, config))) return;
+
+        showAnsibleModal();
+        await window.go.main.App.DeployStacks(host, user, piPass, vol, config, backend);
     } catch (error) {
         console.error('Deployment failed:', error);
         hideAnsibleModal();
-        showAlert('error', `Deployment failed: ${error.message}`);
+        showAlert('error', `Deployment failed: ${error.message || error}`);
+    }
+}
+
+// Save the selected deployment as an offline bundle
+async function exportDeployment() {
+    if (!ensureWails()) return;
+
+    try {
+        const generateSecrets = confirm('Generate real passwords in the bundle? Choose Cancel to leave CHANGE_ME placeholders.');
+        const file = await window.go.main.App.ExportDeployment(host, user, vol, selectedStackConfig(), generateSecrets);
+        if (file) {
+            showAlert('success', `Deployment exported to ${file}`);
+        }
+    } catch (error) {
+        console.error('Export failed:', error);
+        showAlert('error', `Export failed: ${error.message || error}`);
+    }
+}
+
+// Compare the images deployed on the host with the registry
+async function checkImageUpdates() {
+    if (!ensureWails()) return;
+
+    try {
+        const updates = await window.go.main.App.CheckImageUpdates(host);
+        const available = updates.filter(update => update.updateAvailable);
+        const failed = updates.filter(update => update.error);
+        if (available.length === 0 && failed.length === 0) {
+            showAlert('success', `All ${updates.length} images on ${host} are up to date`);
+            return;
+        }
+        const parts = available.map(update => `${update.container} (${update.image})`);
+        let message = available.length > 0 ? `Updates available: ${parts.join(', ')}` : 'No updates available';
+        if (failed.length > 0) {
+            message += `. Could not check: ${failed.map(update => update.container).join(', ')}`;
+        }
+        showAlert(failed.length > 0 ? 'error' : 'success', message);
+    } catch (error) {
+        console.error('Update check failed:', error);
+        showAlert('error', `Update check failed: ${error.message || error}`);
+    }
+}
+
+// Template shown when defining a custom component
+const customComponentTemplate = {
+    id: 'sensor-bridge',
+    name: 'Sensor Bridge',
+    description: 'In-house sensor gateway',
+    image: 'registry.local:5000/sensor-bridge:1.2',
+    ports: ['8085:8080'],
+    env: {},
+    volumes: ['{data}/sensor-bridge:/data'],
+    restart: 'unless-stopped',
+    healthUrl: 'http://{host}:8085/health',
+    architectures: ['arm64'],
+    ramMb: 128,
+};
+
+// Define a custom component and add it to the catalog
+async function addCustomComponent() {
+    if (!ensureWails()) return;
+
+    const text = prompt('Custom component definition (JSON):', JSON.stringify(customComponentTemplate));
+    if (!text) return;
+
+    try {
+        await window.go.main.App.AddCustomComponent(JSON.parse(text));
+        await loadCatalog();
+        showAlert('success', 'Custom component added to the catalog');
+    } catch (error) {
+        console.error('Adding custom component failed:', error);
+        showAlert('error', `Adding custom component failed: ${error.message || error}`);
+    }
+}
+
+// Ticked components, by catalog id
+function selectedComponentIds() {
+    if (!catalog) return [];
+    return catalog.components
+        .filter(component => document.getElementById(`component-${component.flag}`)?.checked)
+        .map(component => component.id);
+}
+
+// Upgrade the ticked components to the newest images, rolling back any that fail
+async function upgradeSelectedComponents() {
+    if (!ensureWails() || !connection) {
+        showAlert('error', 'Please establish connection first');
+        return;
+    }
+
+    const components = selectedComponentIds();
+    if (components.length === 0) {
+        showAlert('error', 'Select the components to upgrade');
+        return;
+    }
+    if (!confirm(`Pull the latest images for ${components.join(', ')} and recreate their containers? Any that do not come up healthy are rolled back.`)) return;
+
+    try {
+        showAnsibleModal();
+        await window.go.main.App.UpgradeComponents(host, user, piPass, vol, components, componentOverrides);
+    } catch (error) {
+        console.error('Upgrade failed:', error);
+        hideAnsibleModal();
+        showAlert('error', `Upgrade failed: ${error.message || error}`);
+    }
+}
+
+// Describe exactly what a removal deletes for confirmation
+function formatRemovalPlan(plan) {
+    const lines = [`Remove ${plan.components.join(', ')}?`, '', 'Containers:'];
+    plan.containers.forEach(item => lines.push(`  ${item.name}${item.exists ? '' : ' (not found)'}`));
+    if (plan.keepData) {
+        lines.push('', 'Data and config directories will be kept.');
+    } else {
+        lines.push('', 'Directories (deleted permanently):');
+        plan.directories.forEach(item => lines.push(`  ${item.name}${item.exists ? ` (${item.size})` : ' (not found)'}`));
+    }
+    if (plan.removeNetwork) {
+        lines.push('', 'The unused docker_network will be removed.');
+    }
+    plan.warnings.forEach(warning => lines.push('', warning));
+    lines.push('', 'Continue?');
+    return lines.join('\n');
+}
+
+// Remove the ticked components from the host
+async function removeSelectedComponents() {
+    if (!ensureWails() || !connection) {
+        showAlert('error', 'Please establish connection first');
+        return;
+    }
+
+    const components = selectedComponentIds();
+    if (components.length === 0) {
+        showAlert('error', 'Select the components to remove');
+        return;
+    }
+
+    try {
+        const keepData = !confirm('Also delete the data and config directories of these components? Choose Cancel to keep them.');
+        const plan = await window.go.main.App.PreviewRemoveComponents(host, user, piPass, vol, components, keepData);
+        if (!confirm(formatRemovalPlan(plan))) return;
+
+        showAnsibleModal();
+        await window.go.main.App.RemoveComponents(host, user, piPass, vol, components, keepData, backend);
+    } catch (error) {
+        console.error('Removal failed:', error);
+        hideAnsibleModal();
+        showAlert('error', `Removal failed: ${error.message || error}`);
     }
 }
 
@@ -420,12 +806,15 @@ async function prepareUSB() {
     }
 
     try {
+        usbDevice = document.getElementById('usbDevice')?.value.trim() || usbDevice;
+        if (!await approvePreview('USB preparation', () => window.go.main.App.PreviewPrepareUSB(host, user, piPass, vol, usbDevice))) return;
+
         showAnsibleModal();
-        await window.go.main.App.PrepareUSB(host, user, piPass, vol);
+        await window.go.main.App.PrepareUSB(host, user, piPass, vol, usbDevice, backend);
     } catch (error) {
         console.error('USB preparation failed:', error);
         hideAnsibleModal();
-        showAlert('error', `USB preparation failed: ${error.message}`);
+        showAlert('error', `USB preparation failed: ${error.message || error}`);
     }
 }
 
@@ -437,12 +826,14 @@ async function prepareNetworkNFS() {
     }
 
     try {
+        if (!await approvePreview('NFS preparation', () => window.go.main.App.PreviewPrepareNetworkNFS(host, user, piPass, vol, netShare, '/docker'))) return;
+
         showAnsibleModal();
-        await window.go.main.App.PrepareNetworkNFS(host, user, piPass, vol, netShare, '/docker');
+        await window.go.main.App.PrepareNetworkNFS(host, user, piPass, vol, netShare, '/docker', backend);
     } catch (error) {
         console.error('NFS preparation failed:', error);
         hideAnsibleModal();
-        showAlert('error', `NFS preparation failed: ${error.message}`);
+        showAlert('error', `NFS preparation failed: ${error.message || error}`);
     }
 }
 
@@ -453,12 +844,14 @@ async function prepareNetworkCIFS() {
     }
 
     try {
+        if (!await approvePreview('CIFS preparation', () => window.go.main.App.PreviewPrepareNetworkCIFS(host, user, piPass, vol, netShare, 'docker', netUser, netPassword))) return;
+
         showAnsibleModal();
-        await window.go.main.App.PrepareNetworkCIFS(host, user, piPass, vol, netShare, 'docker', netUser, netPassword);
+        await window.go.main.App.PrepareNetworkCIFS(host, user, piPass, vol, netShare, 'docker', netUser, netPassword, backend);
     } catch (error) {
         console.error('CIFS preparation failed:', error);
         hideAnsibleModal();
-        showAlert('error', `CIFS preparation failed: ${error.message}`);
+        showAlert('error', `CIFS preparation failed: ${error.message || error}`);
     }
 }
 
@@ -471,11 +864,11 @@ async function updatePi() {
 
     try {
         showAnsibleModal();
-        await window.go.main.App.UpdatePi(host, user, piPass);
+        await window.go.main.App.UpdatePi(host, user, piPass, backend);
     } catch (error) {
         console.error('Pi update failed:', error);
         hideAnsibleModal();
-        showAlert('error', `Pi update failed: ${error.message}`);
+        showAlert('error', `Pi update failed: ${error.message || error}`);
     }
 }
 
@@ -486,12 +879,14 @@ async function installDocker() {
     }
 
     try {
+        if (!await approvePreview('Docker installation', () => window.go.main.App.PreviewInstallDocker(host, user, piPass, vol))) return;
+
         showAnsibleModal();
-        await window.go.main.App.InstallDocker(host, user, piPass, vol);
+        await window.go.main.App.InstallDocker(host, user, piPass, vol, backend);
     } catch (error) {
         console.error('Docker installation failed:', error);
         hideAnsibleModal();
-        showAlert('error', `Docker installation failed: ${error.message}`);
+        showAlert('error', `Docker installation failed: ${error.message || error}`);
     }
 }
 
@@ -503,11 +898,11 @@ async function installPortainer() {
 
     try {
         showAnsibleModal();
-        await window.go.main.App.InstallPortainer(host, user, piPass, vol);
+        await window.go.main.App.InstallPortainer(host, user, piPass, vol, backend);
     } catch (error) {
         console.error('Portainer installation failed:', error);
         hideAnsibleModal();
-        showAlert('error', `Portainer installation failed: ${error.message}`);
+        showAlert('error', `Portainer installation failed: ${error.message || error}`);
     }
 }
 
@@ -528,6 +923,8 @@ function logMessage(message) {
 window.dockerizathinginator = {
     testConnection,
     deployComplete,
+    exportDeployment,
+    checkImageUpdates,
     prepareUSB,
     prepareNetworkNFS,
     prepareNetworkCIFS,
@@ -2,9 +2,15 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function AddCustomComponent(arg1:main.CustomComponent):Promise<void>;
+
+export function CheckImageUpdates(arg1:string):Promise<Array<main.ImageUpdate>>;
+
 export function CreateBackupRepository(arg1:string):Promise<void>;
 
-export function DeployStacks(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig):Promise<void>;
+export function DeployStacks(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig,arg6:string):Promise<main.RunSummary>;
+
+export function DeployStacksCompose(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig,arg6:string):Promise<main.RunSummary>;
 
 export function DisconnectGitHub():Promise<void>;
 
@@ -12,22 +18,86 @@ export function EmitProgress(arg1:string):Promise<void>;
 
 export function EmitStatus(arg1:string,arg2:boolean):Promise<void>;
 
+export function ExportDeployment(arg1:string,arg2:string,arg3:string,arg4:main.StackConfig,arg5:boolean):Promise<string>;
+
+export function GetAnsibleEnvironment():Promise<main.AnsibleEnvironment>;
+
+export function GetCatalog():Promise<main.Catalog>;
+
+export function GetCustomComponents():Promise<Array<main.CustomComponent>>;
+
+export function GetExecutionBackends():Promise<Array<main.ExecutionBackendInfo>>;
+
 export function GetGitHubAuthStatus():Promise<main.GitHubAuthStatus>;
 
+export function GetImageLock(arg1:string):Promise<main.ImageLock>;
+
+export function GetLockedOverrides(arg1:string):Promise<Record<string, main.ComponentOverrides>>;
+
 export function GetModel(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function GetOperationsPolicy(arg1:string):Promise<main.OperationsPolicy>;
+
+export function GetRegistrySettings():Promise<main.RegistrySettings>;
+
+export function GetRunHistory():Promise<Array<main.RunRecord>>;
+
+export function GetRunProfile(arg1:string):Promise<main.RunProfile>;
+
 export function InitiateGitHubAuth():Promise<void>;
 
-export function InstallDocker(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function InstallDocker(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<main.RunSummary>;
+
+export function InstallPortainer(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<main.RunSummary>;
+
+export function ListCustomPlaybooks():Promise<Array<main.CustomPlaybook>>;
+
+export function PlanDeployment(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig):Promise<main.DeploymentPlan>;
+
+export function PlanPorts(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig):Promise<main.PortPlan>;
 
-export function InstallPortainer(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function PrepareNetworkCIFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string):Promise<main.RunSummary>;
 
-export function PrepareNetworkCIFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string):Promise<void>;
+export function PrepareNetworkNFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string):Promise<main.RunSummary>;
 
-export function PrepareNetworkNFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string):Promise<void>;
+export function PrepareUSB(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string):Promise<main.RunSummary>;
 
-export function PrepareUSB(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+export function PreviewDeployStacks(arg1:string,arg2:string,arg3:string,arg4:string,arg5:main.StackConfig):Promise<main.DryRunResult>;
+
+export function PreviewInstallDocker(arg1:string,arg2:string,arg3:string,arg4:string):Promise<main.DryRunResult>;
+
+export function PreviewPrepareNetworkCIFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string):Promise<main.DryRunResult>;
+
+export function PreviewPrepareNetworkNFS(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string):Promise<main.DryRunResult>;
+
+export function PreviewPrepareUSB(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<main.DryRunResult>;
+
+export function PreviewRemoveComponents(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<string>,arg6:boolean):Promise<main.RemovalPlan>;
+
+export function PreviewRemoveStack(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:boolean):Promise<main.RemovalPlan>;
+
+export function RemoveComponents(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<string>,arg6:boolean,arg7:string):Promise<main.RunSummary>;
+
+export function RemoveCustomComponent(arg1:string):Promise<void>;
+
+export function RemoveStack(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:boolean,arg7:string):Promise<main.RunSummary>;
+
+export function RenderComposeProjects(arg1:string,arg2:string,arg3:main.StackConfig):Promise<Array<main.ComposeProject>>;
+
+export function ResumeRun(arg1:string):Promise<main.RunSummary>;
+
+export function RetryFailedHosts(arg1:string):Promise<main.RunSummary>;
+
+export function RunCustomPlaybook(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:Record<string, any>):Promise<main.RunSummary>;
+
+export function RunPlaybookWithOptions(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:Record<string, any>,arg7:main.RunOptions):Promise<main.RunSummary>;
+
+export function SetOperationsPolicy(arg1:string,arg2:main.OperationsPolicy):Promise<void>;
+
+export function SetRegistrySettings(arg1:main.RegistrySettings):Promise<void>;
 
 export function TestSSH(arg1:string,arg2:string,arg3:string):Promise<main.ConnectionResult>;
 
-export function UpdatePi(arg1:string,arg2:string,arg3:string):Promise<void>;
+export function UpdatePi(arg1:string,arg2:string,arg3:string,arg4:string):Promise<main.RunSummary>;
+
+export function UpgradeComponents(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<string>,arg6:Record<string, main.ComponentOverrides>):Promise<main.RunSummary>;
@@ -2,12 +2,24 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddCustomComponent(arg1) {
+  return window['go']['main']['App']['AddCustomComponent'](arg1);
+}
+
+export function CheckImageUpdates(arg1) {
+  return window['go']['main']['App']['CheckImageUpdates'](arg1);
+}
+
 export function CreateBackupRepository(arg1) {
   return window['go']['main']['App']['CreateBackupRepository'](arg1);
 }
 
-export function DeployStacks(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['DeployStacks'](arg1, arg2, arg3, arg4, arg5);
+export function DeployStacks(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['DeployStacks'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function DeployStacksCompose(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['DeployStacksCompose'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
 export function DisconnectGitHub() {
@@ -22,42 +34,170 @@ export function EmitStatus(arg1, arg2) {
   return window['go']['main']['App']['EmitStatus'](arg1, arg2);
 }
 
+export function ExportDeployment(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ExportDeployment'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function GetAnsibleEnvironment() {
+  return window['go']['main']['App']['GetAnsibleEnvironment']();
+}
+
+export function GetCatalog() {
+  return window['go']['main']['App']['GetCatalog']();
+}
+
+export function GetCustomComponents() {
+  return window['go']['main']['App']['GetCustomComponents']();
+}
+
+export function GetExecutionBackends() {
+  return window['go']['main']['App']['GetExecutionBackends']();
+}
+
 export function GetGitHubAuthStatus() {
   return window['go']['main']['App']['GetGitHubAuthStatus']();
 }
 
+export function GetImageLock(arg1) {
+  return window['go']['main']['App']['GetImageLock'](arg1);
+}
+
+export function GetLockedOverrides(arg1) {
+  return window['go']['main']['App']['GetLockedOverrides'](arg1);
+}
+
 export function GetModel(arg1, arg2, arg3) {
   return window['go']['main']['App']['GetModel'](arg1, arg2, arg3);
 }
 
+export function GetOperationsPolicy(arg1) {
+  return window['go']['main']['App']['GetOperationsPolicy'](arg1);
+}
+
+export function GetRegistrySettings() {
+  return window['go']['main']['App']['GetRegistrySettings']();
+}
+
+export function GetRunHistory() {
+  return window['go']['main']['App']['GetRunHistory']();
+}
+
+export function GetRunProfile(arg1) {
+  return window['go']['main']['App']['GetRunProfile'](arg1);
+}
+
 export function InitiateGitHubAuth() {
   return window['go']['main']['App']['InitiateGitHubAuth']();
 }
 
-export function InstallDocker(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['InstallDocker'](arg1, arg2, arg3, arg4);
+export function InstallDocker(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['InstallDocker'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function InstallPortainer(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['InstallPortainer'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ListCustomPlaybooks() {
+  return window['go']['main']['App']['ListCustomPlaybooks']();
+}
+
+export function PlanDeployment(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['PlanDeployment'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function PlanPorts(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['PlanPorts'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function PrepareNetworkCIFS(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9) {
+  return window['go']['main']['App']['PrepareNetworkCIFS'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9);
+}
+
+export function PrepareNetworkNFS(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['PrepareNetworkNFS'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function PrepareUSB(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['PrepareUSB'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function PreviewDeployStacks(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['PreviewDeployStacks'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function InstallPortainer(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['InstallPortainer'](arg1, arg2, arg3, arg4);
+export function PreviewInstallDocker(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['PreviewInstallDocker'](arg1, arg2, arg3, arg4);
 }
 
-export function PrepareNetworkCIFS(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
-  return window['go']['main']['App']['PrepareNetworkCIFS'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
+export function PreviewPrepareNetworkCIFS(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
+  return window['go']['main']['App']['PreviewPrepareNetworkCIFS'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
 }
 
-export function PrepareNetworkNFS(arg1, arg2, arg3, arg4, arg5, arg6) {
-  return window['go']['main']['App']['PrepareNetworkNFS'](arg1, arg2, arg3, arg4, arg5, arg6);
+export function PreviewPrepareNetworkNFS(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['PreviewPrepareNetworkNFS'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
-export function PrepareUSB(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['PrepareUSB'](arg1, arg2, arg3, arg4);
+export function PreviewPrepareUSB(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['PreviewPrepareUSB'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function PreviewRemoveComponents(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['PreviewRemoveComponents'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function PreviewRemoveStack(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['PreviewRemoveStack'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function RemoveComponents(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['RemoveComponents'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function RemoveCustomComponent(arg1) {
+  return window['go']['main']['App']['RemoveCustomComponent'](arg1);
+}
+
+export function RemoveStack(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['RemoveStack'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function RenderComposeProjects(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenderComposeProjects'](arg1, arg2, arg3);
+}
+
+export function ResumeRun(arg1) {
+  return window['go']['main']['App']['ResumeRun'](arg1);
+}
+
+export function RetryFailedHosts(arg1) {
+  return window['go']['main']['App']['RetryFailedHosts'](arg1);
+}
+
+export function RunCustomPlaybook(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['RunCustomPlaybook'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function RunPlaybookWithOptions(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['RunPlaybookWithOptions'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function SetOperationsPolicy(arg1, arg2) {
+  return window['go']['main']['App']['SetOperationsPolicy'](arg1, arg2);
+}
+
+export function SetRegistrySettings(arg1) {
+  return window['go']['main']['App']['SetRegistrySettings'](arg1);
 }
 
 export function TestSSH(arg1, arg2, arg3) {
   return window['go']['main']['App']['TestSSH'](arg1, arg2, arg3);
 }
 
-export function UpdatePi(arg1, arg2, arg3) {
-  return window['go']['main']['App']['UpdatePi'](arg1, arg2, arg3);
+export function UpdatePi(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdatePi'](arg1, arg2, arg3, arg4);
+}
+
+export function UpgradeComponents(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['UpgradeComponents'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
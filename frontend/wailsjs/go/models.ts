@@ -1,5 +1,312 @@
 export namespace main {
 	
+	export class AnsibleCollectionStatus {
+	    name: string;
+	    version?: string;
+	    minVersion: string;
+	    installed: boolean;
+	    ok: boolean;
+	    usedFor: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AnsibleCollectionStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.minVersion = source["minVersion"];
+	        this.installed = source["installed"];
+	        this.ok = source["ok"];
+	        this.usedFor = source["usedFor"];
+	    }
+	}
+	export class EnvironmentIssue {
+	    severity: string;
+	    message: string;
+	    fix: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvironmentIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.severity = source["severity"];
+	        this.message = source["message"];
+	        this.fix = source["fix"];
+	    }
+	}
+	export class AnsibleEnvironment {
+	    installed: boolean;
+	    coreVersion?: string;
+	    minimumVersion: string;
+	    versionOk: boolean;
+	    pythonVersion?: string;
+	    pythonPath?: string;
+	    collections: AnsibleCollectionStatus[];
+	    sshpass: boolean;
+	    containerRuntime?: string;
+	    containerImage?: string;
+	    launcher?: string;
+	    ready: boolean;
+	    issues: EnvironmentIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AnsibleEnvironment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.installed = source["installed"];
+	        this.coreVersion = source["coreVersion"];
+	        this.minimumVersion = source["minimumVersion"];
+	        this.versionOk = source["versionOk"];
+	        this.pythonVersion = source["pythonVersion"];
+	        this.pythonPath = source["pythonPath"];
+	        this.collections = this.convertValues(source["collections"], AnsibleCollectionStatus);
+	        this.sshpass = source["sshpass"];
+	        this.containerRuntime = source["containerRuntime"];
+	        this.containerImage = source["containerImage"];
+	        this.launcher = source["launcher"];
+	        this.ready = source["ready"];
+	        this.issues = this.convertValues(source["issues"], EnvironmentIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BindMount {
+	    source: string;
+	    target: string;
+	    readOnly?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new BindMount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.target = source["target"];
+	        this.readOnly = source["readOnly"];
+	    }
+	}
+	export class CatalogConflict {
+	    component: string;
+	    hostRamBelowMb?: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CatalogConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.component = source["component"];
+	        this.hostRamBelowMb = source["hostRamBelowMb"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class CatalogComponent {
+	    id: string;
+	    name: string;
+	    description: string;
+	    url?: string;
+	    healthUrl?: string;
+	    custom?: boolean;
+	    stack: string;
+	    flag: string;
+	    default: boolean;
+	    image: string;
+	    ports: string[];
+	    volumes: string[];
+	    ramMb: number;
+	    architectures: string[];
+	    dependencies: string[];
+	    conflicts: CatalogConflict[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CatalogComponent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.url = source["url"];
+	        this.healthUrl = source["healthUrl"];
+	        this.custom = source["custom"];
+	        this.stack = source["stack"];
+	        this.flag = source["flag"];
+	        this.default = source["default"];
+	        this.image = source["image"];
+	        this.ports = source["ports"];
+	        this.volumes = source["volumes"];
+	        this.ramMb = source["ramMb"];
+	        this.architectures = source["architectures"];
+	        this.dependencies = source["dependencies"];
+	        this.conflicts = this.convertValues(source["conflicts"], CatalogConflict);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CatalogStack {
+	    id: string;
+	    name: string;
+	    description: string;
+	    flag?: string;
+	    playbook: string;
+	    dirs?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CatalogStack(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.flag = source["flag"];
+	        this.playbook = source["playbook"];
+	        this.dirs = source["dirs"];
+	    }
+	}
+	export class Catalog {
+	    stacks: CatalogStack[];
+	    components: CatalogComponent[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Catalog(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stacks = this.convertValues(source["stacks"], CatalogStack);
+	        this.components = this.convertValues(source["components"], CatalogComponent);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class ComponentOverrides {
+	    ports?: Record<string, number>;
+	    env?: Record<string, string>;
+	    image?: string;
+	    tag?: string;
+	    digest?: string;
+	    timezone?: string;
+	    puid?: number;
+	    pgid?: number;
+	    memoryLimitMb?: number;
+	    mounts?: BindMount[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ComponentOverrides(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ports = source["ports"];
+	        this.env = source["env"];
+	        this.image = source["image"];
+	        this.tag = source["tag"];
+	        this.digest = source["digest"];
+	        this.timezone = source["timezone"];
+	        this.puid = source["puid"];
+	        this.pgid = source["pgid"];
+	        this.memoryLimitMb = source["memoryLimitMb"];
+	        this.mounts = this.convertValues(source["mounts"], BindMount);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ComposeProject {
+	    stack: string;
+	    dir: string;
+	    compose: string;
+	    env: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ComposeProject(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stack = source["stack"];
+	        this.dir = source["dir"];
+	        this.compose = source["compose"];
+	        this.env = source["env"];
+	    }
+	}
 	export class ConnectionResult {
 	    success: boolean;
 	    message: string;
@@ -16,6 +323,321 @@ export namespace main {
 	        this.model = source["model"];
 	    }
 	}
+	export class CustomComponent {
+	    id: string;
+	    name: string;
+	    description: string;
+	    image: string;
+	    ports: string[];
+	    env: Record<string, string>;
+	    volumes: string[];
+	    restart: string;
+	    healthUrl: string;
+	    architectures: string[];
+	    ramMb: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustomComponent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.image = source["image"];
+	        this.ports = source["ports"];
+	        this.env = source["env"];
+	        this.volumes = source["volumes"];
+	        this.restart = source["restart"];
+	        this.healthUrl = source["healthUrl"];
+	        this.architectures = source["architectures"];
+	        this.ramMb = source["ramMb"];
+	    }
+	}
+	export class PlaybookVariable {
+	    name: string;
+	    type: string;
+	    description?: string;
+	    required: boolean;
+	    default?: any;
+	    secret: boolean;
+	    choices?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaybookVariable(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.description = source["description"];
+	        this.required = source["required"];
+	        this.default = source["default"];
+	        this.secret = source["secret"];
+	        this.choices = source["choices"];
+	    }
+	}
+	export class CustomPlaybook {
+	    name: string;
+	    title: string;
+	    description: string;
+	    variables: PlaybookVariable[];
+	    path: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustomPlaybook(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.title = source["title"];
+	        this.description = source["description"];
+	        this.variables = this.convertValues(source["variables"], PlaybookVariable);
+	        this.path = source["path"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StackConfig {
+	    stacks: Record<string, boolean>;
+	    components: Record<string, boolean>;
+	    overrides?: Record<string, ComponentOverrides>;
+	
+	    static createFrom(source: any = {}) {
+	        return new StackConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stacks = source["stacks"];
+	        this.components = source["components"];
+	        this.overrides = this.convertValues(source["overrides"], ComponentOverrides, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlanStep {
+	    component: string;
+	    name: string;
+	    stack: string;
+	    containers: string[];
+	    ramMb: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlanStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.component = source["component"];
+	        this.name = source["name"];
+	        this.stack = source["stack"];
+	        this.containers = source["containers"];
+	        this.ramMb = source["ramMb"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class DeploymentPlan {
+	    steps: PlanStep[];
+	    included: string[];
+	    warnings: string[];
+	    hostRamMb: number;
+	    hostArch?: string;
+	    ramMb: number;
+	    config: StackConfig;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeploymentPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.steps = this.convertValues(source["steps"], PlanStep);
+	        this.included = source["included"];
+	        this.warnings = source["warnings"];
+	        this.hostRamMb = source["hostRamMb"];
+	        this.hostArch = source["hostArch"];
+	        this.ramMb = source["ramMb"];
+	        this.config = this.convertValues(source["config"], StackConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileDiff {
+	    beforeHeader?: string;
+	    afterHeader?: string;
+	    before?: string;
+	    after?: string;
+	    prepared?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.beforeHeader = source["beforeHeader"];
+	        this.afterHeader = source["afterHeader"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	        this.prepared = source["prepared"];
+	    }
+	}
+	export class DryRunTask {
+	    play: string;
+	    task: string;
+	    host: string;
+	    action?: string;
+	    status: string;
+	    message?: string;
+	    diffs?: FileDiff[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DryRunTask(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.play = source["play"];
+	        this.task = source["task"];
+	        this.host = source["host"];
+	        this.action = source["action"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	        this.diffs = this.convertValues(source["diffs"], FileDiff);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DryRunResult {
+	    playbook: string;
+	    changed: number;
+	    failed: number;
+	    unreachable: number;
+	    tasks: DryRunTask[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DryRunResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.playbook = source["playbook"];
+	        this.changed = source["changed"];
+	        this.failed = source["failed"];
+	        this.unreachable = source["unreachable"];
+	        this.tasks = this.convertValues(source["tasks"], DryRunTask);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class ExecutionBackendInfo {
+	    name: string;
+	    description: string;
+	    available: boolean;
+	    default: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExecutionBackendInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.available = source["available"];
+	        this.default = source["default"];
+	    }
+	}
+	
 	export class GitHubAuthStatus {
 	    is_authenticated: boolean;
 	    username?: string;
@@ -34,24 +656,666 @@ export namespace main {
 	        this.error = source["error"];
 	    }
 	}
-	export class StackConfig {
-	    networkStack: boolean;
-	    iotStack: boolean;
-	    mediaStack: boolean;
-	    components: Record<string, boolean>;
+	export class HostSummary {
+	    host: string;
+	    ok: number;
+	    changed: number;
+	    unreachable: number;
+	    failed: number;
+	    skipped: number;
+	    rescued: number;
+	    ignored: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new StackConfig(source);
+	        return new HostSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.ok = source["ok"];
+	        this.changed = source["changed"];
+	        this.unreachable = source["unreachable"];
+	        this.failed = source["failed"];
+	        this.skipped = source["skipped"];
+	        this.rescued = source["rescued"];
+	        this.ignored = source["ignored"];
+	    }
+	}
+	export class LockedImage {
+	    container: string;
+	    image: string;
+	    digest: string;
+	    imageId: string;
+	    // Go type: time
+	    deployedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new LockedImage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.container = source["container"];
+	        this.image = source["image"];
+	        this.digest = source["digest"];
+	        this.imageId = source["imageId"];
+	        this.deployedAt = this.convertValues(source["deployedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImageLock {
+	    host: string;
+	    // Go type: time
+	    updatedAt: any;
+	    images: LockedImage[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageLock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.images = this.convertValues(source["images"], LockedImage);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImageUpdate {
+	    container: string;
+	    image: string;
+	    currentDigest: string;
+	    latestDigest: string;
+	    updateAvailable: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.container = source["container"];
+	        this.image = source["image"];
+	        this.currentDigest = source["currentDigest"];
+	        this.latestDigest = source["latestDigest"];
+	        this.updateAvailable = source["updateAvailable"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	export class OperationsPolicy {
+	    connectTimeoutSeconds: number;
+	    runTimeoutMinutes: number;
+	    retries: number;
+	    retryBackoffSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OperationsPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectTimeoutSeconds = source["connectTimeoutSeconds"];
+	        this.runTimeoutMinutes = source["runTimeoutMinutes"];
+	        this.retries = source["retries"];
+	        this.retryBackoffSeconds = source["retryBackoffSeconds"];
+	    }
+	}
+	export class PhaseComparison {
+	    phase: string;
+	    durationMs: number;
+	    baselineMs: number;
+	    deltaMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PhaseComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.phase = source["phase"];
+	        this.durationMs = source["durationMs"];
+	        this.baselineMs = source["baselineMs"];
+	        this.deltaMs = source["deltaMs"];
+	    }
+	}
+	export class PhaseTiming {
+	    phase: string;
+	    durationMs: number;
+	    tasks: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PhaseTiming(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.phase = source["phase"];
+	        this.durationMs = source["durationMs"];
+	        this.tasks = source["tasks"];
+	    }
+	}
+	
+	
+	export class PortBinding {
+	    component: string;
+	    container: string;
+	    hostPort: number;
+	    protocol: string;
+	    target: string;
+	    defaultPort: number;
+	    fixed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PortBinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.component = source["component"];
+	        this.container = source["container"];
+	        this.hostPort = source["hostPort"];
+	        this.protocol = source["protocol"];
+	        this.target = source["target"];
+	        this.defaultPort = source["defaultPort"];
+	        this.fixed = source["fixed"];
+	    }
+	}
+	export class PortConflict {
+	    port: number;
+	    protocol: string;
+	    holders: string[];
+	    resolution: string;
+	    resolved: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PortConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.port = source["port"];
+	        this.protocol = source["protocol"];
+	        this.holders = source["holders"];
+	        this.resolution = source["resolution"];
+	        this.resolved = source["resolved"];
+	    }
+	}
+	export class PortPlan {
+	    bindings: PortBinding[];
+	    conflicts: PortConflict[];
+	    config: StackConfig;
+	
+	    static createFrom(source: any = {}) {
+	        return new PortPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bindings = this.convertValues(source["bindings"], PortBinding);
+	        this.conflicts = this.convertValues(source["conflicts"], PortConflict);
+	        this.config = this.convertValues(source["config"], StackConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TaskComparison {
+	    task: string;
+	    phase: string;
+	    durationMs: number;
+	    baselineMs: number;
+	    deltaMs: number;
+	    ratio: number;
+	    regression: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TaskComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.task = source["task"];
+	        this.phase = source["phase"];
+	        this.durationMs = source["durationMs"];
+	        this.baselineMs = source["baselineMs"];
+	        this.deltaMs = source["deltaMs"];
+	        this.ratio = source["ratio"];
+	        this.regression = source["regression"];
+	    }
+	}
+	export class ProfileComparison {
+	    baselineRunIds: string[];
+	    totalMs: number;
+	    baselineMs: number;
+	    phases: PhaseComparison[];
+	    tasks: TaskComparison[];
+	    regressions: TaskComparison[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.baselineRunIds = source["baselineRunIds"];
+	        this.totalMs = source["totalMs"];
+	        this.baselineMs = source["baselineMs"];
+	        this.phases = this.convertValues(source["phases"], PhaseComparison);
+	        this.tasks = this.convertValues(source["tasks"], TaskComparison);
+	        this.regressions = this.convertValues(source["regressions"], TaskComparison);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RegistrySettings {
+	    endpoint: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RegistrySettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.endpoint = source["endpoint"];
+	    }
+	}
+	export class RemovalItem {
+	    name: string;
+	    exists: boolean;
+	    size?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemovalItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.exists = source["exists"];
+	        this.size = source["size"];
+	    }
+	}
+	export class RemovalPlan {
+	    components: string[];
+	    containers: RemovalItem[];
+	    directories: RemovalItem[];
+	    removeNetwork: boolean;
+	    keepData: boolean;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RemovalPlan(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.networkStack = source["networkStack"];
-	        this.iotStack = source["iotStack"];
-	        this.mediaStack = source["mediaStack"];
 	        this.components = source["components"];
+	        this.containers = this.convertValues(source["containers"], RemovalItem);
+	        this.directories = this.convertValues(source["directories"], RemovalItem);
+	        this.removeNetwork = source["removeNetwork"];
+	        this.keepData = source["keepData"];
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RunOptions {
+	    tags?: string[];
+	    skipTags?: string[];
+	    startAtTask?: string;
+	    limit?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RunOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tags = source["tags"];
+	        this.skipTags = source["skipTags"];
+	        this.startAtTask = source["startAtTask"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class TaskTiming {
+	    play: string;
+	    task: string;
+	    phase: string;
+	    // Go type: time
+	    startedAt: any;
+	    durationMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TaskTiming(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.play = source["play"];
+	        this.task = source["task"];
+	        this.phase = source["phase"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.durationMs = source["durationMs"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
+	export class RunProfile {
+	    runId: string;
+	    playbook: string;
+	    host: string;
+	    // Go type: time
+	    startedAt: any;
+	    totalMs: number;
+	    tasks: TaskTiming[];
+	    slowestTasks: TaskTiming[];
+	    phases: PhaseTiming[];
+	    comparison?: ProfileComparison;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.runId = source["runId"];
+	        this.playbook = source["playbook"];
+	        this.host = source["host"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.totalMs = source["totalMs"];
+	        this.tasks = this.convertValues(source["tasks"], TaskTiming);
+	        this.slowestTasks = this.convertValues(source["slowestTasks"], TaskTiming);
+	        this.phases = this.convertValues(source["phases"], PhaseTiming);
+	        this.comparison = this.convertValues(source["comparison"], ProfileComparison);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UpgradedContainer {
+	    container: string;
+	    image: string;
+	    previousImageId: string;
+	    previousDigest?: string;
+	    imageId: string;
+	    digest?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpgradedContainer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.container = source["container"];
+	        this.image = source["image"];
+	        this.previousImageId = source["previousImageId"];
+	        this.previousDigest = source["previousDigest"];
+	        this.imageId = source["imageId"];
+	        this.digest = source["digest"];
+	    }
+	}
+	export class UpgradeResult {
+	    component: string;
+	    status: string;
+	    containers: UpgradedContainer[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpgradeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.component = source["component"];
+	        this.status = source["status"];
+	        this.containers = this.convertValues(source["containers"], UpgradedContainer);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RunSummary {
+	    hosts: HostSummary[];
+	    recap: boolean;
+	    success: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hosts = this.convertValues(source["hosts"], HostSummary);
+	        this.recap = source["recap"];
+	        this.success = source["success"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RunRecord {
+	    id: string;
+	    playbook: string;
+	    host: string;
+	    user: string;
+	    backend: string;
+	    extraVars: Record<string, any>;
+	    options: RunOptions;
+	    secretVars?: string[];
+	    custom?: boolean;
+	    compose?: string;
+	    resumedFrom?: string;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    finishedAt: any;
+	    status: string;
+	    error?: string;
+	    failedTask?: string;
+	    failedHosts?: string[];
+	    summary: RunSummary;
+	    upgrades?: UpgradeResult[];
+	    canResume: boolean;
+	    canRetry: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RunRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.playbook = source["playbook"];
+	        this.host = source["host"];
+	        this.user = source["user"];
+	        this.backend = source["backend"];
+	        this.extraVars = source["extraVars"];
+	        this.options = this.convertValues(source["options"], RunOptions);
+	        this.secretVars = source["secretVars"];
+	        this.custom = source["custom"];
+	        this.compose = source["compose"];
+	        this.resumedFrom = source["resumedFrom"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.failedTask = source["failedTask"];
+	        this.failedHosts = source["failedHosts"];
+	        this.summary = this.convertValues(source["summary"], RunSummary);
+	        this.upgrades = this.convertValues(source["upgrades"], UpgradeResult);
+	        this.canResume = source["canResume"];
+	        this.canRetry = source["canRetry"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+	
 
 }
 
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/zalando/go-keyring"
+)
+
+// maxRunHistory is how many runs are kept in the history file
+const maxRunHistory = 50
+
+const (
+	runStatusRunning   = "running"
+	runStatusSucceeded = "succeeded"
+	runStatusFailed    = "failed"
+)
+
+// RunRecord describes one playbook run in the history
+type RunRecord struct {
+	ID          string                 `json:"id"`
+	Playbook    string                 `json:"playbook"`
+	Host        string                 `json:"host"`
+	User        string                 `json:"user"`
+	Backend     string                 `json:"backend"`
+	ExtraVars   map[string]interface{} `json:"extraVars"`
+	Options     RunOptions             `json:"options"`
+	SecretVars  []string               `json:"secretVars,omitempty"`
+	Custom      bool                   `json:"custom,omitempty"`
+	Compose     string                 `json:"compose,omitempty"`
+	ResumedFrom string                 `json:"resumedFrom,omitempty"`
+	StartedAt   time.Time              `json:"startedAt"`
+	FinishedAt  time.Time              `json:"finishedAt"`
+	Status      string                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	FailedTask  string                 `json:"failedTask,omitempty"`
+	FailedHosts []string               `json:"failedHosts,omitempty"`
+	Summary     RunSummary             `json:"summary"`
+	Upgrades    []UpgradeResult        `json:"upgrades,omitempty"`
+	CanResume   bool                   `json:"canResume"`
+	CanRetry    bool                   `json:"canRetry"`
+}
+
+// runCredentials are the secrets of a run, kept in the keyring rather than the history file
+type runCredentials struct {
+	Password   string                 `json:"password"`
+	SecretVars map[string]interface{} `json:"secretVars,omitempty"`
+}
+
+// runHistory persists run records in the user's config directory
+type runHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newRunHistory creates a history stored under the user config directory
+func newRunHistory() *runHistory {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &runHistory{path: filepath.Join(dir, serviceName, "history.json")}
+}
+
+// load reads every record, newest first
+func (h *runHistory) load() ([]RunRecord, error) {
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return []RunRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %v", err)
+	}
+
+	var records []RunRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse run history: %v", err)
+	}
+	return records, nil
+}
+
+// save writes records, dropping the oldest beyond maxRunHistory along with their credentials and timings
+func (h *runHistory) save(records []RunRecord) error {
+	for len(records) > maxRunHistory {
+		keyring.Delete(serviceName, runCredentialsKey(records[len(records)-1].ID))
+		os.Remove(h.profilePath(records[len(records)-1].ID))
+		records = records[:len(records)-1]
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run history: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	// Write then rename so a crash never leaves a truncated history
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run history: %v", err)
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// get returns the record with the given id
+func (h *runHistory) get(id string) (RunRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	records, err := h.load()
+	if err != nil {
+		return RunRecord{}, err
+	}
+	for _, record := range records {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	return RunRecord{}, fmt.Errorf("run %s not found in history", id)
+}
+
+// put inserts or replaces a record
+func (h *runHistory) put(record RunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	records, err := h.load()
+	if err != nil {
+		// A corrupt history should not block deployments
+		records = []RunRecord{}
+	}
+
+	for i := range records {
+		if records[i].ID == record.ID {
+			records[i] = record
+			return h.save(records)
+		}
+	}
+	return h.save(append([]RunRecord{record}, records...))
+}
+
+// start records a new run and stores its secrets in the keyring
+func (h *runHistory) start(job PlaybookJob, resumedFrom string) (RunRecord, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return RunRecord{}, fmt.Errorf("failed to generate run id: %v", err)
+	}
+
+	plainVars, secretVars := job.splitVars()
+	record := RunRecord{
+		ID:          time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(id),
+		Playbook:    job.Playbook,
+		Host:        job.Host,
+		User:        job.User,
+		Backend:     job.Backend,
+		ExtraVars:   plainVars,
+		Options:     job.Options,
+		SecretVars:  job.SecretVars,
+		Custom:      job.Custom,
+		Compose:     job.Compose,
+		ResumedFrom: resumedFrom,
+		StartedAt:   time.Now(),
+		Status:      runStatusRunning,
+	}
+	// Vars with secrets nested inside are kept with those secrets masked, so the
+	// record still shows them; resuming restores the real values from the keyring
+	for key, value := range secretVars {
+		if !job.isSecretVar(key) {
+			record.ExtraVars[key] = maskSecrets(value)
+		}
+	}
+
+	credentials, err := json.Marshal(runCredentials{Password: job.Password, SecretVars: secretVars})
+	if err != nil {
+		return RunRecord{}, fmt.Errorf("failed to marshal run credentials: %v", err)
+	}
+	if err := keyring.Set(serviceName, runCredentialsKey(record.ID), string(credentials)); err != nil {
+		// Without the keyring the run still works, it just cannot be resumed
+		log.Printf("failed to store run credentials in keyring: %v", err)
+	}
+
+	return record, h.put(record)
+}
+
+// finish records the outcome of a run
+func (h *runHistory) finish(record RunRecord, tracker *runTracker, runErr error) error {
+	record.FinishedAt = time.Now()
+	record.FailedTask, record.FailedHosts = tracker.failure()
+	record.Summary = tracker.summary()
+	record.Upgrades = tracker.upgradeResults()
+	if timings := tracker.taskTimings(); len(timings) > 0 {
+		if err := h.saveTimings(record.ID, timings); err != nil {
+			log.Printf("failed to save task timings: %v", err)
+		}
+	}
+
+	if runErr == nil {
+		record.Status = runStatusSucceeded
+		// Successful runs are never resumed, so their secrets are not needed
+		keyring.Delete(serviceName, runCredentialsKey(record.ID))
+	} else {
+		record.Status = runStatusFailed
+		record.Error = runErr.Error()
+		_, err := keyring.Get(serviceName, runCredentialsKey(record.ID))
+		// Upgrades snapshot containers as they go, so they are run again rather than resumed
+		record.CanResume = record.FailedTask != "" && err == nil && record.Playbook != upgradePlaybook
+		record.CanRetry = len(record.FailedHosts) > 0 && err == nil
+	}
+
+	return h.put(record)
+}
+
+// job rebuilds the job for a recorded run, restoring its secrets from the keyring
+func (h *runHistory) job(record RunRecord) (PlaybookJob, error) {
+	data, err := keyring.Get(serviceName, runCredentialsKey(record.ID))
+	if err != nil {
+		return PlaybookJob{}, fmt.Errorf("credentials for run %s are no longer available: %v", record.ID, err)
+	}
+
+	var credentials runCredentials
+	if err := json.Unmarshal([]byte(data), &credentials); err != nil {
+		return PlaybookJob{}, fmt.Errorf("failed to unmarshal run credentials: %v", err)
+	}
+
+	vars := map[string]interface{}{}
+	for key, value := range record.ExtraVars {
+		vars[key] = value
+	}
+	for key, value := range credentials.SecretVars {
+		vars[key] = value
+	}
+
+	return PlaybookJob{
+		Playbook:   record.Playbook,
+		Host:       record.Host,
+		User:       record.User,
+		Password:   credentials.Password,
+		ExtraVars:  vars,
+		Options:    record.Options,
+		SecretVars: record.SecretVars,
+		Custom:     record.Custom,
+		Compose:    record.Compose,
+		Backend:    record.Backend,
+	}, nil
+}
+
+// runCredentialsKey is the keyring entry holding a run's secrets
+func runCredentialsKey(id string) string {
+	return "run-" + id
+}
+
+// runJob executes a job on its backend and records it in the history
+func (a *App) runJob(job PlaybookJob, resumedFrom string) (RunSummary, error) {
+	if err := validatePlaybookName(job.Playbook); err != nil {
+		return RunSummary{}, fmt.Errorf("playbook validation failed: %v", err)
+	}
+	if err := job.Options.validate(); err != nil {
+		return RunSummary{}, err
+	}
+
+	backend, err := a.backendFor(job)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	job.Backend = backend.Name()
+	job.Policy = a.operations.get(job.Host)
+	record, err := a.history.start(job, resumedFrom)
+	if err != nil {
+		log.Printf("failed to record run: %v", err)
+	}
+
+	tracker := &runTracker{}
+	redactor := newJobRedactor(job)
+	ctx := withRedactor(withRunTracker(a.ctx, tracker), redactor)
+	if timeout := job.Policy.runTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	stream := newOutputStream(ctx)
+	runErr := redactor.redactError(backend.Run(withOutputStream(ctx, stream), job))
+	stream.close()
+
+	if record.ID != "" {
+		if err := a.history.finish(record, tracker, runErr); err != nil {
+			log.Printf("failed to record run result: %v", err)
+		}
+	}
+
+	// Even a failed run may have replaced containers, so the lock is refreshed either way
+	if deploysContainers(job) {
+		if err := a.recordImageLock(job); err != nil {
+			log.Printf("failed to record image lock: %v", err)
+		}
+	}
+	return tracker.summary(), runErr
+}
+
+// GetRunHistory returns recorded runs, newest first
+func (a *App) GetRunHistory() ([]RunRecord, error) {
+	a.history.mu.Lock()
+	defer a.history.mu.Unlock()
+	return a.history.load()
+}
+
+// ResumeRun re-runs a failed run starting at the task that failed
+func (a *App) ResumeRun(id string) (RunSummary, error) {
+	record, err := a.history.get(id)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	if record.Status != runStatusFailed || record.FailedTask == "" {
+		return RunSummary{}, fmt.Errorf("run %s has no failed task to resume from", id)
+	}
+
+	job, err := a.history.job(record)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	job.Options.StartAtTask = record.FailedTask
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Resuming %s from task: %s", record.Playbook, record.FailedTask))
+	return a.runJob(job, record.ID)
+}
+
+// RetryFailedHosts re-runs a failed run against only the hosts that failed
+func (a *App) RetryFailedHosts(id string) (RunSummary, error) {
+	record, err := a.history.get(id)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	if record.Status != runStatusFailed || len(record.FailedHosts) == 0 {
+		return RunSummary{}, fmt.Errorf("run %s has no failed hosts to retry", id)
+	}
+
+	job, err := a.history.job(record)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	job.Options.Limit = record.FailedHosts
+	job.Options.StartAtTask = ""
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Retrying %s on failed hosts...", record.Playbook))
+	return a.runJob(job, record.ID)
+}
+
+// RunPlaybookWithOptions runs a bundled playbook limited to selected tags, hosts or a
+// starting task, on the named backend or the default one if backend is empty
+func (a *App) RunPlaybookWithOptions(host, user, password, playbook, backend string, extraVars map[string]interface{}, options RunOptions) (RunSummary, error) {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateExtraVars(v, "extraVars", extraVars)
+	if err := v.err(); err != nil {
+		return RunSummary{}, err
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", fmt.Sprintf("Running %s...", playbook))
+	return a.runJob(PlaybookJob{
+		Playbook:  playbook,
+		Host:      host,
+		User:      user,
+		Password:  password,
+		ExtraVars: extraVars,
+		Options:   options,
+		Backend:   backend,
+	}, "")
+}
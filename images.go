@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dockerHubRegistry is where images without a registry host are pulled from
+const dockerHubRegistry = "https://registry-1.docker.io"
+
+// manifestMediaTypes are the manifest formats asked for when resolving a tag, so the
+// digest returned is the same multi-arch index digest docker records when it pulls
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// bearerParamPattern matches one key="value" pair of a WWW-Authenticate challenge
+var bearerParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// LockedImage is the exact image a managed container was deployed from
+type LockedImage struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	// Digest is the registry digest the image was pulled by, empty for local builds
+	Digest     string    `json:"digest"`
+	ImageID    string    `json:"imageId"`
+	DeployedAt time.Time `json:"deployedAt"`
+}
+
+// ImageLock records what is deployed on a host
+type ImageLock struct {
+	Host      string        `json:"host"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Images    []LockedImage `json:"images"`
+}
+
+// ImageUpdate compares a deployed image with the registry's current image for its tag
+type ImageUpdate struct {
+	Container       string `json:"container"`
+	Image           string `json:"image"`
+	CurrentDigest   string `json:"currentDigest"`
+	LatestDigest    string `json:"latestDigest"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+	Error           string `json:"error,omitempty"`
+}
+
+// RegistrySettings configures where image updates are looked up. Endpoint replaces
+// Docker Hub, such as a local pull-through mirror; empty uses Docker Hub itself.
+type RegistrySettings struct {
+	Endpoint string `json:"endpoint"`
+}
+
+// validate checks that the endpoint is an http(s) URL
+func (s RegistrySettings) validate(v *ValidationError) {
+	if s.Endpoint == "" {
+		return
+	}
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("endpoint", "must be an http or https URL such as http://mirror.local:5000")
+	}
+}
+
+// imageRef is an image reference split into the parts the registry API needs
+type imageRef struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseImageRef splits an image like pihole/pihole:latest or ghcr.io/org/app:1@sha256:...
+func parseImageRef(image string) imageRef {
+	var ref imageRef
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, ref.digest = image[:i], image[i+1:]
+	}
+	ref.repository, ref.tag = splitImage(image)
+
+	first, rest, found := strings.Cut(ref.repository, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.registry, ref.repository = first, rest
+	} else if !found {
+		ref.repository = "library/" + ref.repository
+	}
+	return ref
+}
+
+// registryClient resolves image tags to digests
+type registryClient struct {
+	http     *http.Client
+	endpoint string
+}
+
+// newRegistryClient creates a client that uses endpoint in place of Docker Hub
+func newRegistryClient(settings RegistrySettings) *registryClient {
+	endpoint := strings.TrimSuffix(settings.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = dockerHubRegistry
+	}
+	return &registryClient{http: &http.Client{Timeout: 20 * time.Second}, endpoint: endpoint}
+}
+
+// digest returns the digest the registry currently serves for an image's tag
+func (c *registryClient) digest(ctx context.Context, ref imageRef) (string, error) {
+	base := c.endpoint
+	if ref.registry != "" {
+		base = "https://" + ref.registry
+	}
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", base, ref.repository, ref.tag)
+
+	resp, err := c.manifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.token(ctx, challenge)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = c.manifest(ctx, manifestURL, token); err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry returned %s for %s:%s", resp.Status, ref.repository, ref.tag)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %s:%s", ref.repository, ref.tag)
+	}
+	return digest, nil
+}
+
+// manifest requests a manifest's headers, with a bearer token when given
+func (c *registryClient) manifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %v", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry: %v", err)
+	}
+	return resp, nil
+}
+
+// token fetches an anonymous pull token for a WWW-Authenticate Bearer challenge
+func (c *registryClient) token(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("registry requires unsupported authentication: %s", challenge)
+	}
+	params := map[string]string{}
+	for _, match := range bearerParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry sent an invalid auth realm: %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch registry token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %v", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, nil
+}
+
+// imageLocks stores one lock file per host under the user config directory
+type imageLocks struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// newImageLocks creates a lock store under the user config directory
+func newImageLocks() *imageLocks {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &imageLocks{dir: filepath.Join(dir, serviceName, "locks")}
+}
+
+// path returns the lock file of a host
+func (l *imageLocks) path(host string) string {
+	return filepath.Join(l.dir, strings.NewReplacer(":", "_", "/", "_").Replace(host)+".json")
+}
+
+// get reads a host's lock; a host never deployed to has an empty lock
+func (l *imageLocks) get(host string) (ImageLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock := ImageLock{Host: host, Images: []LockedImage{}}
+	data, err := os.ReadFile(l.path(host))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, fmt.Errorf("failed to read image lock: %v", err)
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return lock, fmt.Errorf("failed to parse image lock: %v", err)
+	}
+	return lock, nil
+}
+
+// save writes a host's lock
+func (l *imageLocks) save(lock ImageLock) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal image lock: %v", err)
+	}
+	if err := os.MkdirAll(l.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	return os.WriteFile(l.path(lock.Host), data, 0600)
+}
+
+// lockInspectScript prints "name|image|image id|created|repo digests" for every managed container
+const lockInspectScript = `ids=$(docker ps -aq --filter label=com.dockerizathinginator.managed=true)
+[ -z "$ids" ] && exit 0
+docker inspect --format '{{.Name}}|{{.Config.Image}}|{{.Image}}|{{.Created}}' $ids | while IFS='|' read -r name image id created; do
+  echo "$name|$image|$id|$created|$(docker image inspect --format '{{join .RepoDigests ","}}' "$id" 2>/dev/null)"
+done`
+
+// parseLockInspect reads the output of lockInspectScript into locked images
+func parseLockInspect(output string) []LockedImage {
+	images := []LockedImage{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "|", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		image := LockedImage{
+			Container: strings.TrimPrefix(fields[0], "/"),
+			Image:     fields[1],
+			ImageID:   fields[2],
+		}
+		image.DeployedAt, _ = time.Parse(time.RFC3339Nano, fields[3])
+
+		// Prefer the digest of the repository the image was deployed from
+		repository := parseImageRef(image.Image)
+		for _, repoDigest := range strings.Split(fields[4], ",") {
+			name, digest, found := strings.Cut(repoDigest, "@")
+			if !found {
+				continue
+			}
+			ref := parseImageRef(name)
+			if image.Digest == "" || (ref.registry == repository.registry && ref.repository == repository.repository) {
+				image.Digest = digest
+			}
+		}
+		images = append(images, image)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Container < images[j].Container })
+	return images
+}
+
+// deploysContainers reports whether a job creates, updates or removes stack containers
+func deploysContainers(job PlaybookJob) bool {
+	switch job.Playbook {
+	case "main.yml", removePlaybook, upgradePlaybook:
+		return true
+	}
+	return job.Compose != "" || strings.HasPrefix(job.Playbook, "deploy-")
+}
+
+// recordImageLock reads the images of every managed container on the job's host
+// and saves them as the host's lock
+func (a *App) recordImageLock(job PlaybookJob) error {
+	output, err := hostSudo(job.Host, job.User, job.Password, job.Policy.connectTimeout(), lockInspectScript)
+	if err != nil {
+		return fmt.Errorf("failed to inspect deployed images: %v", err)
+	}
+	return a.locks.save(ImageLock{
+		Host:      job.Host,
+		UpdatedAt: time.Now(),
+		Images:    parseLockInspect(output),
+	})
+}
+
+// GetImageLock returns the images recorded for a host by its last deployment
+func (a *App) GetImageLock(host string) (ImageLock, error) {
+	return a.locks.get(host)
+}
+
+// GetLockedOverrides returns overrides that pin each component's main container to
+// the digest recorded for host, so another host can be deployed identically
+func (a *App) GetLockedOverrides(host string) (map[string]ComponentOverrides, error) {
+	lock, err := a.locks.get(host)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := map[string]ComponentOverrides{}
+	for _, image := range lock.Images {
+		for _, component := range currentCatalog().Components {
+			if component.mainContainer().Name != image.Container || image.Digest == "" {
+				continue
+			}
+			repository, tag := splitImage(strings.SplitN(image.Image, "@", 2)[0])
+			overrides[component.ID] = ComponentOverrides{Image: repository, Tag: tag, Digest: image.Digest}
+		}
+	}
+	return overrides, nil
+}
+
+// CheckImageUpdates compares the digests recorded for host with the digests the
+// registry serves for the same tags and reports which images have newer versions
+func (a *App) CheckImageUpdates(host string) ([]ImageUpdate, error) {
+	lock, err := a.locks.get(host)
+	if err != nil {
+		return nil, err
+	}
+	if len(lock.Images) == 0 {
+		return nil, fmt.Errorf("no deployment has been recorded for %s", host)
+	}
+
+	settings, err := a.registry.get()
+	if err != nil {
+		return nil, err
+	}
+	client := newRegistryClient(settings)
+
+	latest := map[string]string{}
+	failures := map[string]error{}
+	updates := []ImageUpdate{}
+	for _, image := range lock.Images {
+		update := ImageUpdate{Container: image.Container, Image: image.Image, CurrentDigest: image.Digest}
+		ref := parseImageRef(image.Image)
+		key := ref.registry + "/" + ref.repository + ":" + ref.tag
+
+		if _, ok := latest[key]; !ok && failures[key] == nil {
+			digest, err := client.digest(a.ctx, ref)
+			if err != nil {
+				log.Printf("Could not check %s for updates: %v", image.Image, err)
+				failures[key] = err
+			}
+			latest[key] = digest
+		}
+
+		switch {
+		case failures[key] != nil:
+			update.Error = failures[key].Error()
+		case ref.digest != "":
+			// Pinned images keep their digest until the pin is changed
+			update.LatestDigest = latest[key]
+		default:
+			update.LatestDigest = latest[key]
+			update.UpdateAvailable = image.Digest != "" && latest[key] != image.Digest
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// registrySettings stores the registry configuration under the user config directory
+type registrySettings struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newRegistrySettings creates the registry settings store
+func newRegistrySettings() *registrySettings {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &registrySettings{path: filepath.Join(dir, serviceName, "registry.json")}
+}
+
+// get reads the settings, defaulting to Docker Hub
+func (r *registrySettings) get() (RegistrySettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var settings RegistrySettings
+	data, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("failed to read registry settings: %v", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse registry settings: %v", err)
+	}
+	return settings, nil
+}
+
+// set saves the settings
+func (r *registrySettings) set(settings RegistrySettings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry settings: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+// GetRegistrySettings returns the registry used to check for image updates
+func (a *App) GetRegistrySettings() (RegistrySettings, error) {
+	return a.registry.get()
+}
+
+// SetRegistrySettings saves the registry used to check for image updates
+func (a *App) SetRegistrySettings(settings RegistrySettings) error {
+	v := &ValidationError{}
+	settings.validate(v)
+	if err := v.err(); err != nil {
+		return err
+	}
+	return a.registry.set(settings)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		image string
+		want  imageRef
+	}{
+		{"nginx", imageRef{repository: "library/nginx", tag: "latest"}},
+		{"pihole/pihole:latest", imageRef{repository: "pihole/pihole", tag: "latest"}},
+		{"linuxserver/sonarr:4.0.1", imageRef{repository: "linuxserver/sonarr", tag: "4.0.1"}},
+		{"ghcr.io/org/app:1@sha256:abc", imageRef{registry: "ghcr.io", repository: "org/app", tag: "1", digest: "sha256:abc"}},
+		{"registry.local:5000/sonarr", imageRef{registry: "registry.local:5000", repository: "sonarr", tag: "latest"}},
+		{"localhost/app:dev", imageRef{registry: "localhost", repository: "app", tag: "dev"}},
+		{"grafana/grafana@sha256:def", imageRef{repository: "grafana/grafana", tag: "latest", digest: "sha256:def"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseImageRef(tt.image); got != tt.want {
+			t.Errorf("parseImageRef(%q) = %+v, want %+v", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestParseLockInspect(t *testing.T) {
+	output := `/sonarr|linuxserver/sonarr:latest|sha256:111|2026-03-01T10:00:00.123456789Z|lscr.io/linuxserver/sonarr@sha256:aaa,linuxserver/sonarr@sha256:bbb
+/grafana|grafana/grafana:latest|sha256:222|2026-03-02T11:00:00Z|grafana/grafana@sha256:ccc
+/sensor-bridge|sensor-bridge:dev|sha256:333|2026-03-03T12:00:00Z|
+not a lock line
+`
+	want := []LockedImage{
+		{Container: "grafana", Image: "grafana/grafana:latest", Digest: "sha256:ccc", ImageID: "sha256:222", DeployedAt: time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC)},
+		{Container: "sensor-bridge", Image: "sensor-bridge:dev", ImageID: "sha256:333", DeployedAt: time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{Container: "sonarr", Image: "linuxserver/sonarr:latest", Digest: "sha256:bbb", ImageID: "sha256:111", DeployedAt: time.Date(2026, 3, 1, 10, 0, 0, 123456789, time.UTC)},
+	}
+
+	got := parseLockInspect(output)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLockInspect() = %+v\nwant %+v", got, want)
+	}
+	if empty := parseLockInspect(""); empty == nil || len(empty) != 0 {
+		t.Errorf("parseLockInspect(\"\") = %#v, want an empty list", empty)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sshPasswordEnv carries the SSH password to ansible-playbook without writing it to disk
+const sshPasswordEnv = "DOCKERIZATHINGINATOR_SSH_PASSWORD"
+
+// inventoryGroup and inventoryHost name the single host every run targets
+const (
+	inventoryGroup = "raspberrypi"
+	inventoryHost  = "pi"
+)
+
+// inventoryVar is a single host variable in a generated inventory
+type inventoryVar struct {
+	name  string
+	value string
+	// template values are Jinja expressions Ansible must evaluate; everything else is literal
+	template bool
+}
+
+// buildInventory renders a YAML inventory for host. The password is never written
+// out; instead the inventory looks it up from sshPasswordEnv at run time.
+func buildInventory(host, user string, withPassword bool) []byte {
+	vars := []inventoryVar{
+		{name: "ansible_host", value: host},
+		{name: "ansible_user", value: user},
+	}
+	if withPassword {
+		lookup := fmt.Sprintf("{{ lookup('env', '%s') }}", sshPasswordEnv)
+		vars = append(vars,
+			inventoryVar{name: "ansible_password", value: lookup, template: true},
+			inventoryVar{name: "ansible_become_password", value: lookup, template: true},
+		)
+	}
+
+	var b strings.Builder
+	b.WriteString("all:\n")
+	b.WriteString("  children:\n")
+	fmt.Fprintf(&b, "    %s:\n", inventoryGroup)
+	b.WriteString("      hosts:\n")
+	fmt.Fprintf(&b, "        %s:\n", inventoryHost)
+	for _, v := range vars {
+		fmt.Fprintf(&b, "          %s: %s\n", v.name, yamlScalar(v.value, v.template))
+	}
+	return []byte(b.String())
+}
+
+// yamlScalar encodes s as a double-quoted YAML scalar. JSON string syntax is a
+// subset of YAML's double-quoted style, so quotes, colons, comment markers and
+// newlines cannot escape the value. Literal values are tagged !unsafe so Ansible
+// does not treat braces in them as templates.
+func yamlScalar(s string, template bool) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	quoted := strings.TrimSuffix(b.String(), "\n")
+
+	if template {
+		return quoted
+	}
+	return "!unsafe " + quoted
+}
+
+// writeInventory writes the inventory for a run into dir, which must be private to the run
+func writeInventory(dir, host, user string, withPassword bool) (string, error) {
+	path := filepath.Join(dir, "inventory.yml")
+	if err := os.WriteFile(path, buildInventory(host, user, withPassword), 0600); err != nil {
+		return "", fmt.Errorf("failed to write inventory: %v", err)
+	}
+	return path, nil
+}
+
+// writeExtraVars writes a run's non-secret extra-vars into dir for ansible-playbook
+func writeExtraVars(dir string, vars map[string]interface{}) (string, error) {
+	data, err := encodeVarsYAML(vars)
+	if err != nil {
+		return "", err
+	}
+	// ansible-playbook rejects an empty vars file, so no vars is an empty mapping
+	if len(data) == 0 {
+		data = []byte("{}\n")
+	}
+	path := filepath.Join(dir, "extra-vars.yml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write extra-vars: %v", err)
+	}
+	return path, nil
+}
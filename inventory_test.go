@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYAMLScalar(t *testing.T) {
+	tests := []struct {
+		in       string
+		template bool
+		want     string
+	}{
+		{"pi", false, `!unsafe "pi"`},
+		{`a"b`, false, `!unsafe "a\"b"`},
+		{"line\nansible_become: yes", false, `!unsafe "line\nansible_become: yes"`},
+		{"x # not a comment", false, `!unsafe "x # not a comment"`},
+		{"{{ evil }}", false, `!unsafe "{{ evil }}"`},
+		{"{{ lookup('env', 'X') }}", true, `"{{ lookup('env', 'X') }}"`},
+	}
+
+	for _, tt := range tests {
+		if got := yamlScalar(tt.in, tt.template); got != tt.want {
+			t.Errorf("yamlScalar(%q, %v) = %s, want %s", tt.in, tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	inventory := string(buildInventory("raspberrypi.local", "pi", true))
+
+	for _, line := range []string{
+		`          ansible_host: !unsafe "raspberrypi.local"`,
+		`          ansible_user: !unsafe "pi"`,
+		`          ansible_password: "{{ lookup('env', '` + sshPasswordEnv + `') }}"`,
+		`          ansible_become_password: "{{ lookup('env', '` + sshPasswordEnv + `') }}"`,
+	} {
+		if !strings.Contains(inventory, line+"\n") {
+			t.Errorf("inventory is missing %q:\n%s", line, inventory)
+		}
+	}
+
+	if without := string(buildInventory("10.0.0.2", "pi", false)); strings.Contains(without, "ansible_password") {
+		t.Errorf("inventory without a password sets one:\n%s", without)
+	}
+}
+
+func TestBuildInventoryKeepsValuesOnOneLine(t *testing.T) {
+	inventory := string(buildInventory("host\n          ansible_become_exe: evil", "pi", false))
+	if strings.Contains(inventory, "\n          ansible_become_exe") {
+		t.Errorf("a newline in the host added a variable:\n%s", inventory)
+	}
+}
+
+func TestWriteExtraVars(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := writeExtraVars(dir, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("writeExtraVars() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{}\n" {
+		t.Errorf("empty vars file = %q, want an empty mapping", data)
+	}
+
+	path, err = writeExtraVars(dir, map[string]interface{}{"volume_path": "/mnt/docker"})
+	if err != nil {
+		t.Fatalf("writeExtraVars() error = %v", err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("vars file written to %s, want it in %s", path, dir)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("vars file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
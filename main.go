@@ -12,6 +12,9 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+//go:embed all:ansible
+var ansibleAssets embed.FS
+
 func main() {
 	// Create an instance of the app structure
 	app := NewApp()
@@ -29,6 +32,7 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 30, G: 30, B: 46, A: 1}, // Catppuccin base color
 		OnStartup:        app.startup,
 		OnShutdown:       app.shutdown,
+		ErrorFormatter:   formatBindingError,
 		Bind: []interface{}{
 			app,
 		},
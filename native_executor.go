@@ -0,0 +1,1111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/ssh"
+)
+
+// nativeInventoryHost is the host name used in task output, matching the generated inventory
+const nativeInventoryHost = inventoryHost
+
+// NativeBackend performs the playbook steps directly over SSH, without Ansible
+type NativeBackend struct {
+	runner *AnsibleRunner
+}
+
+// NewNativeBackend creates a new NativeBackend
+func NewNativeBackend(runner *AnsibleRunner) *NativeBackend {
+	return &NativeBackend{runner: runner}
+}
+
+// Name returns the backend identifier
+func (b *NativeBackend) Name() string {
+	return backendNative
+}
+
+// Description returns a human readable summary of the backend
+func (b *NativeBackend) Description() string {
+	return "Performs the same steps directly over SSH; no Ansible or WSL required"
+}
+
+// Available reports whether the backend can be used; it only needs SSH
+func (b *NativeBackend) Available() bool {
+	return true
+}
+
+// Run executes the job over a dedicated SSH connection
+func (b *NativeBackend) Run(ctx context.Context, job PlaybookJob) error {
+	run, err := newNativeRun(ctx, b.runner, job)
+	if err != nil {
+		outputStreamFrom(ctx).close()
+		runtime.EventsEmit(ctx, "ansibleError", err.Error())
+		return err
+	}
+	defer run.client.Close()
+
+	// Closing the connection aborts the command in progress when the run times out
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			run.client.Close()
+		case <-done:
+		}
+	}()
+
+	err = run.execute()
+	if err == nil && run.startAt != "" {
+		err = fmt.Errorf("no task named %q was found", run.startAt)
+	}
+	if ctx.Err() != nil {
+		err = runContextError(ctx, job.Policy)
+	}
+	run.recap()
+
+	if err != nil {
+		return b.runner.finishRun(ctx, fmt.Errorf("native deployment failed: %v", err))
+	}
+	return b.runner.finishRun(ctx, nil)
+}
+
+// nativeRun holds the state of one native execution
+type nativeRun struct {
+	ctx     context.Context
+	runner  *AnsibleRunner
+	client  *ssh.Client
+	job     PlaybookJob
+	vars    map[string]interface{}
+	secrets map[string]string
+	// startAt is the task to resume from; earlier tasks are passed over until it is reached
+	startAt string
+
+	ok, changed, failed, skipped int
+}
+
+// newNativeRun connects to the host and prepares a run
+func newNativeRun(ctx context.Context, runner *AnsibleRunner, job PlaybookJob) (*nativeRun, error) {
+	if job.Custom {
+		return nil, fmt.Errorf("custom playbooks need Ansible; select the Ansible backend to run %s", job.Playbook)
+	}
+	if len(job.Options.Tags) > 0 || len(job.Options.SkipTags) > 0 {
+		return nil, fmt.Errorf("the native executor does not support tags; use the Ansible backend")
+	}
+	if len(job.Options.Limit) > 0 && !containsString(job.Options.Limit, nativeInventoryHost) {
+		return nil, fmt.Errorf("no hosts matched the limit %s", strings.Join(job.Options.Limit, ","))
+	}
+
+	client, err := dialSSH(job.Host, job.User, job.Password, job.Policy.connectTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", job.Host, err)
+	}
+
+	vars := job.ExtraVars
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	return &nativeRun{
+		ctx:     ctx,
+		runner:  runner,
+		client:  client,
+		job:     job,
+		vars:    vars,
+		secrets: map[string]string{},
+		startAt: job.Options.StartAtTask,
+	}, nil
+}
+
+// execute dispatches to the native equivalent of the job's playbook
+func (r *nativeRun) execute() error {
+	if r.job.Compose != "" {
+		return r.composeDeploy()
+	}
+
+	switch r.job.Playbook {
+	case "main.yml":
+		return r.mainPlaybook()
+	case "install-docker.yml":
+		r.play("Install Docker on Raspberry Pi")
+		return r.installDocker()
+	case "configure-storage.yml":
+		r.play("Configure Storage on Raspberry Pi")
+		return r.configureStorage()
+	case "deploy-portainer.yml":
+		r.play("Deploy Portainer on Raspberry Pi")
+		return r.deployStack("portainer")
+	case "deploy-network-stack.yml":
+		r.play("Deploy Network Stack on Raspberry Pi")
+		return r.deployStack("network")
+	case "deploy-iot-stack.yml":
+		r.play("Deploy IoT Stack on Raspberry Pi")
+		return r.deployStack("iot")
+	case "deploy-media-stack.yml":
+		r.play("Deploy Media Stack on Raspberry Pi")
+		return r.deployStack("media")
+	case "deploy-custom-stack.yml":
+		r.play("Deploy Custom Stack on Raspberry Pi")
+		return r.deployStack(customStack)
+	case upgradePlaybook:
+		r.play("Upgrade Components on Raspberry Pi")
+		return r.upgradeComponents()
+	case removePlaybook:
+		r.play("Remove Components from Raspberry Pi")
+		return r.removeComponents()
+	default:
+		return fmt.Errorf("playbook %s is not supported by the native executor", r.job.Playbook)
+	}
+}
+
+// mainPlaybook mirrors main.yml
+func (r *nativeRun) mainPlaybook() error {
+	r.play("Complete Raspberry Pi Docker Setup")
+
+	var model string
+	if err := r.task("Verify Raspberry Pi model", func() (bool, error) {
+		out, err := r.exec("cat /proc/device-tree/model")
+		model = strings.Trim(strings.TrimSpace(out), "\x00")
+		return false, err
+	}); err != nil {
+		return err
+	}
+	r.line(fmt.Sprintf("ok: [%s] => Detected: %s", nativeInventoryHost, model))
+
+	if err := r.task("System requirements check", func() (bool, error) {
+		disk, err := r.exec("df -BG / | awk 'NR==2 {print $4}' | tr -d 'G'")
+		if err != nil {
+			return false, err
+		}
+		memory, err := r.exec("free -m | awk 'NR==2 {print $2}'")
+		if err != nil {
+			return false, err
+		}
+		diskGB, _ := strconv.Atoi(strings.TrimSpace(disk))
+		memoryMB, _ := strconv.Atoi(strings.TrimSpace(memory))
+		if diskGB <= 2 || memoryMB < 1024 {
+			return false, fmt.Errorf("Insufficient resources. Need at least 2GB free disk space and 1GB RAM.")
+		}
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	if !r.boolVar("skip_update", false) {
+		if err := r.task("Update Raspberry Pi OS", r.aptUpgrade); err != nil {
+			return err
+		}
+	}
+
+	if !r.boolVar("skip_docker", false) {
+		if err := r.installDocker(); err != nil {
+			return err
+		}
+	}
+
+	if !r.boolVar("skip_storage", false) {
+		if err := r.configureStorage(); err != nil {
+			return err
+		}
+	}
+
+	if r.boolVar("deploy_portainer", true) {
+		if err := r.deployStack("portainer"); err != nil {
+			return err
+		}
+	}
+
+	for _, stack := range []string{"network", "iot", "media", customStack} {
+		if r.boolVar("deploy_"+stack+"_stack", false) {
+			if err := r.deployStack(stack); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := r.task("Clean up package cache", func() (bool, error) {
+		_, err := r.sudo("DEBIAN_FRONTEND=noninteractive apt-get -y autoclean && DEBIAN_FRONTEND=noninteractive apt-get -y autoremove")
+		return false, err
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Collect deployed services", func() (bool, error) {
+		out, err := r.sudo(`docker ps --format "table {{.Names}}\t{{.Status}}\t{{.Ports}}"`)
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			r.line(line)
+		}
+		return false, err
+	})
+}
+
+// installDocker mirrors install-docker.yml
+func (r *nativeRun) installDocker() error {
+	steps := []struct {
+		name string
+		fn   func() (bool, error)
+	}{
+		{"Update and upgrade apt packages", r.aptUpgrade},
+		{"Install required system packages", func() (bool, error) {
+			return r.aptInstall("apt-transport-https", "ca-certificates", "curl", "gnupg", "lsb-release", "software-properties-common")
+		}},
+		{"Add Docker's official GPG key", func() (bool, error) {
+			if _, err := r.sudo("test -f /etc/apt/keyrings/docker.asc"); err == nil {
+				return false, nil
+			}
+			_, err := r.sudo("install -d -m 0755 /etc/apt/keyrings && curl -fsSL https://download.docker.com/linux/debian/gpg -o /etc/apt/keyrings/docker.asc && chmod 0644 /etc/apt/keyrings/docker.asc")
+			return err == nil, err
+		}},
+		{"Add Docker repository", func() (bool, error) {
+			arch, err := r.exec("dpkg --print-architecture")
+			if err != nil {
+				return false, err
+			}
+			codename, err := r.exec("lsb_release -cs")
+			if err != nil {
+				return false, err
+			}
+			repo := fmt.Sprintf("deb [arch=%s signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/debian %s stable\n",
+				strings.TrimSpace(arch), strings.TrimSpace(codename))
+			return r.writeFile(templateFile{Path: "/etc/apt/sources.list.d/docker.list", Content: repo, Mode: "0644"})
+		}},
+		{"Install Docker Engine", func() (bool, error) {
+			if _, err := r.sudo("DEBIAN_FRONTEND=noninteractive apt-get update"); err != nil {
+				return false, err
+			}
+			return r.aptInstall("docker-ce", "docker-ce-cli", "containerd.io", "docker-buildx-plugin", "docker-compose-plugin")
+		}},
+		{"Ensure Docker service is started and enabled", func() (bool, error) {
+			_, err := r.sudo("systemctl enable --now docker")
+			return false, err
+		}},
+		{"Add pi user to docker group", func() (bool, error) {
+			if out, _ := r.exec("id -nG"); strings.Contains(" "+strings.TrimSpace(out)+" ", " docker ") {
+				return false, nil
+			}
+			_, err := r.sudo("usermod -aG docker " + shellQuote(r.job.User))
+			return err == nil, err
+		}},
+		{"Configure Docker daemon for log rotation", func() (bool, error) {
+			changed, err := r.writeFile(templateFile{
+				Path:    "/etc/docker/daemon.json",
+				Content: "{\n  \"log-driver\": \"json-file\",\n  \"log-opts\": {\n    \"max-size\": \"10m\",\n    \"max-file\": \"3\"\n  },\n  \"storage-driver\": \"overlay2\"\n}\n",
+				Mode:    "0644",
+			})
+			if err == nil && changed {
+				_, err = r.sudo("systemctl restart docker")
+			}
+			return changed, err
+		}},
+		{"Create docker network for containers", r.ensureDockerNetwork},
+	}
+
+	for _, step := range steps {
+		if err := r.task(step.name, step.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	usbDevicePattern = regexp.MustCompile(`^(sd[b-z]|nvme[0-9]{1,2}n[1-9]|mmcblk[0-9])$`)
+)
+
+// configureStorage mirrors configure-storage.yml
+func (r *nativeRun) configureStorage() error {
+	storageType := r.stringVar("storage_type", "usb")
+	mountPoint := r.stringVar("mount_point", r.stringVar("volume_path", "/mnt/docker"))
+
+	var err error
+	switch storageType {
+	case "usb":
+		err = r.configureUSB(mountPoint)
+	case "nfs":
+		err = r.configureNFS(mountPoint)
+	case "cifs":
+		err = r.configureCIFS(mountPoint)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.boolVar("enable_log2ram", true) {
+		if err := r.configureLog2Ram(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.task("Create Docker directories on storage", func() (bool, error) {
+		var dirs []string
+		for _, dir := range []string{"docker", "docker/volumes", "docker/config", "docker/data", "backups"} {
+			dirs = append(dirs, path.Join(mountPoint, dir))
+		}
+		return r.mkdirs("root", dirs...)
+	}); err != nil {
+		return err
+	}
+
+	if storageType != "default" {
+		if err := r.task("Update Docker daemon configuration", func() (bool, error) {
+			changed, err := r.writeFile(templateFile{
+				Path:    "/etc/docker/daemon.json",
+				Content: fmt.Sprintf("{\n  \"data-root\": %q,\n  \"log-driver\": \"json-file\",\n  \"log-opts\": {\n    \"max-size\": \"10m\",\n    \"max-file\": \"3\"\n  },\n  \"storage-driver\": \"overlay2\"\n}\n", path.Join(mountPoint, "docker")),
+				Mode:    "0644",
+			})
+			if err == nil && changed {
+				_, err = r.sudo("systemctl restart docker")
+			}
+			return changed, err
+		}); err != nil {
+			return err
+		}
+	}
+
+	return r.task("Verify storage configuration", func() (bool, error) {
+		out, err := r.exec("df -h " + shellQuote(mountPoint))
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			r.line(line)
+		}
+		return false, err
+	})
+}
+
+// partitionPath returns the first partition of a disk. Devices whose names end in
+// a digit, such as nvme0n1 and mmcblk0, separate the partition number with a "p".
+func partitionPath(dev string) string {
+	if last := dev[len(dev)-1]; last >= '0' && last <= '9' {
+		return dev + "p1"
+	}
+	return dev + "1"
+}
+
+// configureUSB prepares and mounts a USB drive
+func (r *nativeRun) configureUSB(mountPoint string) error {
+	device := r.stringVar("usb_device", "")
+
+	if err := r.task("Install required packages for USB storage", func() (bool, error) {
+		return r.aptInstall("parted", "e2fsprogs", "usbutils")
+	}); err != nil {
+		return err
+	}
+
+	if err := r.task("Validate USB device name format", func() (bool, error) {
+		if device == "" {
+			return false, fmt.Errorf("No USB storage device selected. Please connect a USB drive.")
+		}
+		if !usbDevicePattern.MatchString(device) {
+			return false, fmt.Errorf("Invalid USB device name: %s. System disk sda and partitions are forbidden.", device)
+		}
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	dev := "/dev/" + device
+	partition := partitionPath(dev)
+	if err := r.task("Verify device is removable", func() (bool, error) {
+		out, _ := r.exec(fmt.Sprintf("cat /sys/block/%s/removable 2>/dev/null || echo 0", device))
+		if strings.TrimSpace(out) != "1" {
+			return false, fmt.Errorf("Device %s is not marked as removable. Refusing to format system devices for safety.", device)
+		}
+		if _, err := r.exec("test -b " + shellQuote(dev)); err != nil {
+			return false, fmt.Errorf("Device %s does not exist", dev)
+		}
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	if r.boolVar("format_usb", false) {
+		if err := r.task("Format partition with ext4", func() (bool, error) {
+			_, err := r.sudo(fmt.Sprintf("umount %[2]s 2>/dev/null; wipefs -a -f %[1]s && parted -s %[1]s mklabel gpt mkpart primary ext4 0%% 100%% && mkfs.ext4 -F -L DOCKER_STORAGE %[3]s",
+				shellQuote(dev), shellQuote(mountPoint), shellQuote(partition)))
+			return err == nil, err
+		}); err != nil {
+			return err
+		}
+	}
+
+	var uuid string
+	if err := r.task("Get partition UUID", func() (bool, error) {
+		out, err := r.sudo("blkid -s UUID -o value " + shellQuote(partition))
+		uuid = strings.TrimSpace(out)
+		if err == nil && uuid == "" {
+			err = fmt.Errorf("no filesystem found on %s", partition)
+		}
+		return false, err
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Mount USB storage", func() (bool, error) {
+		return r.mount(mountPoint, fmt.Sprintf("UUID=%s %s ext4 defaults,noatime 0 2", uuid, mountPoint))
+	})
+}
+
+// configureNFS mounts an NFS share
+func (r *nativeRun) configureNFS(mountPoint string) error {
+	if err := r.task("Install NFS client", func() (bool, error) {
+		return r.aptInstall("nfs-common")
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Mount NFS share", func() (bool, error) {
+		source := fmt.Sprintf("%s:%s", r.stringVar("nfs_server", ""), r.stringVar("nfs_path", ""))
+		options := r.stringVar("nfs_options", "defaults,_netdev,soft,intr,rsize=8192,wsize=8192")
+		return r.mount(mountPoint, fmt.Sprintf("%s %s nfs %s 0 0", source, mountPoint, options))
+	})
+}
+
+// configureCIFS mounts a CIFS/SMB share
+func (r *nativeRun) configureCIFS(mountPoint string) error {
+	if err := r.task("Install CIFS utilities", func() (bool, error) {
+		return r.aptInstall("cifs-utils")
+	}); err != nil {
+		return err
+	}
+
+	username, password := r.stringVar("smb_username", ""), r.stringVar("smb_password", "")
+	if username == "" || password == "" {
+		r.skip("Mount CIFS share")
+		return nil
+	}
+
+	if err := r.task("Create credentials file", func() (bool, error) {
+		return r.writeFile(templateFile{
+			Path:    "/etc/samba/docker-share.creds",
+			Content: fmt.Sprintf("username=%s\npassword=%s\ndomain=%s\n", username, password, r.stringVar("smb_domain", "WORKGROUP")),
+			Mode:    "0600",
+		})
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Mount CIFS share", func() (bool, error) {
+		source := fmt.Sprintf("//%s/%s", r.stringVar("smb_server", ""), r.stringVar("smb_share", ""))
+		options := "credentials=/etc/samba/docker-share.creds,uid=1000,gid=1000,iocharset=utf8,file_mode=0777,dir_mode=0777,_netdev"
+		return r.mount(mountPoint, fmt.Sprintf("%s %s cifs %s 0 0", source, mountPoint, options))
+	})
+}
+
+// configureLog2Ram installs and configures Log2Ram
+func (r *nativeRun) configureLog2Ram() error {
+	if err := r.task("Run Log2Ram installation script", func() (bool, error) {
+		if _, err := r.exec("test -x /usr/local/bin/log2ram"); err == nil {
+			return false, nil
+		}
+		if _, err := r.aptInstall("git"); err != nil {
+			return false, err
+		}
+		_, err := r.sudo("rm -rf /tmp/log2ram && git clone --depth 1 https://github.com/azlux/log2ram.git /tmp/log2ram && cd /tmp/log2ram && ./install.sh")
+		return err == nil, err
+	}); err != nil {
+		return err
+	}
+
+	if err := r.task("Configure Log2Ram settings", func() (bool, error) {
+		settings := [][2]string{
+			{"SIZE", "128M"}, {"MAIL", "false"}, {"PATH_DISK", `"/var/log"`}, {"ZL2R", "false"}, {"COMP_ALG", "lz4"},
+		}
+		var script []string
+		for _, s := range settings {
+			line := s[0] + "=" + s[1]
+			script = append(script, fmt.Sprintf("(grep -q %[1]s /etc/log2ram.conf || { sed -i '/^%[2]s=/d' /etc/log2ram.conf; echo %[1]s >> /etc/log2ram.conf; echo changed; })",
+				shellQuote("^"+regexp.QuoteMeta(line)+"$"), s[0]))
+		}
+		out, err := r.sudo(strings.Join(script, " && "))
+		return strings.Contains(out, "changed"), err
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Enable and start Log2Ram service", func() (bool, error) {
+		_, err := r.sudo("systemctl daemon-reload && systemctl enable --now log2ram")
+		return false, err
+	})
+}
+
+// deployStack mirrors one of the deploy-*.yml playbooks
+func (r *nativeRun) deployStack(stack string) error {
+	if stack != "portainer" {
+		if err := r.task("Ensure Docker network exists", r.ensureDockerNetwork); err != nil {
+			return err
+		}
+		if err := r.task(fmt.Sprintf("Create directories for %s stack", stack), func() (bool, error) {
+			return r.mkdirs("root", r.expandAll(currentCatalog().stackDirs()[stack])...)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range currentCatalog().containerSpecs() {
+		if spec.Stack != stack {
+			continue
+		}
+		if !r.boolVar(spec.Flag, spec.Default) {
+			r.skip(fmt.Sprintf("Deploy %s container", spec.Name))
+			continue
+		}
+		if err := r.deployContainer(r.customize(spec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deployContainer creates a single container from its spec, replacing any existing one
+func (r *nativeRun) deployContainer(spec containerSpec) error {
+	if err := r.prepareContainer(spec); err != nil {
+		return err
+	}
+
+	if err := r.task(fmt.Sprintf("Deploy %s container", spec.Name), func() (bool, error) {
+		if _, err := r.sudo("docker pull " + shellQuote(spec.Image)); err != nil {
+			return false, err
+		}
+		if _, err := r.sudo("docker rm -f " + shellQuote(spec.Name) + " >/dev/null 2>&1 || true"); err != nil {
+			return false, err
+		}
+		_, err := r.sudo(shellJoin(dockerRunArgs(spec, r.expand)))
+		return err == nil, err
+	}); err != nil {
+		return err
+	}
+
+	return r.finishContainer(spec)
+}
+
+// prepareContainer creates the directories and configuration files a container needs
+func (r *nativeRun) prepareContainer(spec containerSpec) error {
+	if len(spec.Dirs) > 0 {
+		if err := r.task(fmt.Sprintf("Create %s directories", spec.Name), func() (bool, error) {
+			return r.mkdirs(spec.DirOwner, r.expandAll(spec.Dirs)...)
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range spec.Files {
+		if file.When != "" && !r.boolVar(file.When, flagDefault(file.When)) {
+			continue
+		}
+		file := r.expandFile(file)
+		if err := r.task(fmt.Sprintf("Create %s configuration", path.Base(file.Path)), func() (bool, error) {
+			return r.writeFile(file)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishContainer runs a started container's setup commands and saves its credentials
+func (r *nativeRun) finishContainer(spec containerSpec) error {
+	for _, command := range spec.PostCommands {
+		command := r.expand(command)
+		if err := r.task(fmt.Sprintf("Configure %s", spec.Name), func() (bool, error) {
+			_, err := r.sudo(command)
+			return err == nil, err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if spec.Credentials != nil {
+		file := r.expandFile(*spec.Credentials)
+		if err := r.task(fmt.Sprintf("Save %s credentials", spec.Name), func() (bool, error) {
+			return r.writeFile(file)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dockerRunArgs builds the docker run command line for a spec
+func dockerRunArgs(spec containerSpec, expand func(string) string) []string {
+	restart := spec.Restart
+	if restart == "" {
+		restart = "unless-stopped"
+	}
+
+	args := []string{"docker", "run", "-d", "--name", spec.Name, "--restart", restart}
+	if spec.NetworkMode != "" {
+		args = append(args, "--network", spec.NetworkMode)
+	} else {
+		args = append(args, "--network", "docker_network")
+	}
+	if spec.Memory != "" {
+		args = append(args, "--memory", spec.Memory)
+	}
+	for _, port := range spec.Ports {
+		args = append(args, "-p", port)
+	}
+	for _, key := range sortedKeys(spec.Env) {
+		args = append(args, "-e", key+"="+expand(spec.Env[key]))
+	}
+	for _, volume := range spec.Volumes {
+		args = append(args, "-v", expand(volume))
+	}
+	for _, device := range spec.Devices {
+		args = append(args, "--device", device)
+	}
+	for _, capability := range spec.Capabilities {
+		args = append(args, "--cap-add", capability)
+	}
+	for _, key := range sortedKeys(spec.Sysctls) {
+		args = append(args, "--sysctl", key+"="+spec.Sysctls[key])
+	}
+	args = append(args,
+		"--label", "com.dockerizathinginator.managed=true",
+		"--label", "com.dockerizathinginator.stack="+spec.Stack,
+		"--label", "com.dockerizathinginator.service="+spec.Name,
+		spec.Image,
+	)
+	for _, arg := range spec.Command {
+		args = append(args, expand(arg))
+	}
+	return args
+}
+
+// customize applies the per-container extra-vars the playbooks accept to a spec
+func (r *nativeRun) customize(spec containerSpec) containerSpec {
+	prefix := strings.ReplaceAll(spec.Name, "-", "_")
+	if ports, ok := r.listVar(portsVar(spec.Name)); ok {
+		spec.Ports = ports
+	}
+	if image := r.stringVar(prefix+"_image", ""); image != "" {
+		spec.Image = image
+	}
+	if overrides, ok := r.vars[prefix+"_env"].(map[string]interface{}); ok {
+		env := map[string]string{}
+		for name, value := range spec.Env {
+			env[name] = value
+		}
+		for name, value := range overrides {
+			env[name] = fmt.Sprint(value)
+		}
+		spec.Env = env
+	}
+	spec.Memory = r.stringVar(prefix+"_memory", spec.Memory)
+	if mounts, ok := r.vars[prefix+"_mounts"].([]interface{}); ok {
+		spec.Volumes = append([]string{}, spec.Volumes...)
+		for _, item := range mounts {
+			mount, _ := item.(map[string]interface{})
+			volume := fmt.Sprintf("%v:%v", mount["source"], mount["target"])
+			if readOnly, _ := mount["read_only"].(bool); readOnly {
+				volume += ":ro"
+			}
+			spec.Volumes = append(spec.Volumes, volume)
+		}
+	}
+	return spec
+}
+
+// flagDefault returns the playbook default for a component flag
+func flagDefault(flag string) bool {
+	for _, spec := range currentCatalog().containerSpecs() {
+		if spec.Flag == flag {
+			return spec.Default
+		}
+	}
+	return false
+}
+
+// ensureDockerNetwork creates the shared bridge network if it is missing
+func (r *nativeRun) ensureDockerNetwork() (bool, error) {
+	if _, err := r.sudo("docker network inspect docker_network >/dev/null 2>&1"); err == nil {
+		return false, nil
+	}
+	_, err := r.sudo("docker network create --driver bridge docker_network")
+	return err == nil, err
+}
+
+// aptUpgrade refreshes the package cache and applies a dist-upgrade
+func (r *nativeRun) aptUpgrade() (bool, error) {
+	out, err := r.sudo("DEBIAN_FRONTEND=noninteractive apt-get update && DEBIAN_FRONTEND=noninteractive apt-get -y dist-upgrade")
+	return !strings.Contains(out, "0 upgraded, 0 newly installed"), err
+}
+
+// aptInstall installs any of the packages that are missing
+func (r *nativeRun) aptInstall(packages ...string) (bool, error) {
+	var missing []string
+	for _, pkg := range packages {
+		out, err := r.exec(fmt.Sprintf("dpkg-query -W -f='${Status}' %s 2>/dev/null", shellQuote(pkg)))
+		if err != nil || !strings.Contains(out, "install ok installed") {
+			missing = append(missing, pkg)
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	quoted := make([]string, len(missing))
+	for i, pkg := range missing {
+		quoted[i] = shellQuote(pkg)
+	}
+	_, err := r.sudo("DEBIAN_FRONTEND=noninteractive apt-get install -y " + strings.Join(quoted, " "))
+	return err == nil, err
+}
+
+// mkdirs creates directories owned by owner
+func (r *nativeRun) mkdirs(owner string, dirs ...string) (bool, error) {
+	if len(dirs) == 0 {
+		return false, nil
+	}
+	if owner == "" {
+		owner = "root"
+	}
+
+	quoted := make([]string, len(dirs))
+	for i, dir := range dirs {
+		quoted[i] = shellQuote(dir)
+	}
+	list := strings.Join(quoted, " ")
+
+	// Only directories this run creates are given to owner; existing ones, and the
+	// user data in them, keep their ownership
+	created, err := r.sudo(fmt.Sprintf("for d in %[2]s; do if [ ! -d \"$d\" ]; then install -d -m 0755 -o %[1]s -g %[1]s \"$d\" && echo \"$d\" || exit 1; fi; done",
+		shellQuote(owner), list))
+	return strings.TrimSpace(created) != "", err
+}
+
+// mount ensures an fstab entry for the mount point and mounts it
+func (r *nativeRun) mount(mountPoint, entry string) (bool, error) {
+	if _, err := r.mkdirs("root", mountPoint); err != nil {
+		return false, err
+	}
+
+	changed := false
+	if _, err := r.exec("grep -qxF " + shellQuote(entry) + " /etc/fstab"); err != nil {
+		script := fmt.Sprintf("awk -v mp=%s '$2 != mp' /etc/fstab > /etc/fstab.dockerizathinginator && echo %s >> /etc/fstab.dockerizathinginator && cp /etc/fstab /etc/fstab.bak && mv /etc/fstab.dockerizathinginator /etc/fstab",
+			shellQuote(mountPoint), shellQuote(entry))
+		if _, err := r.sudo(script); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if _, err := r.exec("mountpoint -q " + shellQuote(mountPoint)); err != nil {
+		if _, err := r.sudo("mount " + shellQuote(mountPoint)); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// writeFile renders a file onto the host, reporting whether it changed
+func (r *nativeRun) writeFile(file templateFile) (bool, error) {
+	owner := file.Owner
+	if owner == "" {
+		owner = "root"
+	}
+	mode := file.Mode
+	if mode == "" {
+		mode = "0600"
+	}
+
+	current, err := r.sudo("cat " + shellQuote(file.Path))
+	exists := err == nil
+	if exists && (file.KeepExisting || current == file.Content) {
+		return false, nil
+	}
+
+	tmp, err := r.exec("mktemp")
+	if err != nil {
+		return false, err
+	}
+	tmp = strings.TrimSpace(tmp)
+
+	if err := r.upload(tmp, file.Content); err != nil {
+		return false, err
+	}
+
+	_, err = r.sudo(fmt.Sprintf("install -D -m %s -o %s -g %s %s %s; rc=$?; rm -f %s; exit $rc",
+		shellQuote(mode), shellQuote(owner), shellQuote(owner), shellQuote(tmp), shellQuote(file.Path), shellQuote(tmp)))
+	return err == nil, err
+}
+
+// upload writes content to a path the SSH user can write to
+func (r *nativeRun) upload(dest, content string) error {
+	session, err := r.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %v", err)
+	}
+	defer session.Close()
+
+	session.Stdin = strings.NewReader(content)
+	if out, err := session.CombinedOutput("umask 077 && cat > " + shellQuote(dest)); err != nil {
+		return fmt.Errorf("failed to upload %s: %v: %s", dest, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// exec runs a command as the SSH user
+func (r *nativeRun) exec(command string) (string, error) {
+	return r.run(command, nil)
+}
+
+// sudo runs a command as root, supplying the SSH password to sudo on stdin
+func (r *nativeRun) sudo(command string) (string, error) {
+	if r.job.User == "root" {
+		return r.run("sh -c "+shellQuote(command), nil)
+	}
+	return r.run("sudo -S -p '' sh -c "+shellQuote(command), strings.NewReader(r.job.Password+"\n"))
+}
+
+// run executes a command in a new SSH session
+func (r *nativeRun) run(command string, stdin *strings.Reader) (string, error) {
+	if err := r.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	session, err := r.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to open SSH session: %v", err)
+	}
+	defer session.Close()
+
+	if stdin != nil {
+		session.Stdin = stdin
+	}
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(command); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return stdout.String(), fmt.Errorf("%v: %s", err, msg)
+	}
+	return stdout.String(), nil
+}
+
+// play emits a play header
+func (r *nativeRun) play(name string) {
+	r.line(fmt.Sprintf("PLAY [%s] %s", name, strings.Repeat("*", 40)))
+}
+
+// task runs one step, emitting Ansible-style task and result lines
+func (r *nativeRun) task(name string, fn func() (bool, error)) error {
+	if r.passOver(name) {
+		return nil
+	}
+	r.line(fmt.Sprintf("TASK [%s] %s", name, strings.Repeat("*", 40)))
+
+	changed, err := fn()
+	for attempt := 1; err != nil && attempt <= r.job.Policy.Retries && r.ctx.Err() == nil; attempt++ {
+		reason := transientReason(err.Error())
+		if reason == "" {
+			break
+		}
+		r.line(fmt.Sprintf("FAILED - RETRYING: [%s]: %s (%d retries left).", nativeInventoryHost, name, r.job.Policy.Retries-attempt+1))
+		if r.job.Policy.waitToRetry(r.ctx, attempt, name, reason) != nil {
+			break
+		}
+		changed, err = fn()
+	}
+	if err != nil {
+		r.failed++
+		msg, _ := json.Marshal(map[string]string{"msg": err.Error()})
+		r.line(fmt.Sprintf("fatal: [%s]: FAILED! => %s", nativeInventoryHost, msg))
+		return fmt.Errorf("task %q failed: %v", name, err)
+	}
+
+	r.ok++
+	if changed {
+		r.changed++
+		r.line(fmt.Sprintf("changed: [%s]", nativeInventoryHost))
+	} else {
+		r.line(fmt.Sprintf("ok: [%s]", nativeInventoryHost))
+	}
+	return nil
+}
+
+// skip records a step that was not needed
+func (r *nativeRun) skip(name string) {
+	if r.passOver(name) {
+		return
+	}
+	r.skipped++
+	r.line(fmt.Sprintf("TASK [%s] %s", name, strings.Repeat("*", 40)))
+	r.line(fmt.Sprintf("skipping: [%s]", nativeInventoryHost))
+}
+
+// passOver reports whether a task comes before the one a resumed run starts at
+func (r *nativeRun) passOver(name string) bool {
+	if r.startAt == "" || r.startAt == name {
+		r.startAt = ""
+		return false
+	}
+	return true
+}
+
+// recap emits a PLAY RECAP in Ansible's format
+func (r *nativeRun) recap() {
+	r.line(fmt.Sprintf("PLAY RECAP %s", strings.Repeat("*", 40)))
+	r.line(fmt.Sprintf("%-26s : ok=%d changed=%d unreachable=0 failed=%d skipped=%d rescued=0 ignored=0",
+		nativeInventoryHost, r.ok, r.changed, r.failed, r.skipped))
+}
+
+// line emits a line of output through the shared Ansible output path
+func (r *nativeRun) line(line string) {
+	r.runner.emitLine(r.ctx, "stdout", line)
+}
+
+// boolVar reads a boolean extra-var, accepting Ansible's truthy strings
+func (r *nativeRun) boolVar(name string, def bool) bool {
+	switch value := r.vars[name].(type) {
+	case bool:
+		return value
+	case string:
+		switch strings.ToLower(value) {
+		case "true", "yes", "1", "on":
+			return true
+		case "false", "no", "0", "off":
+			return false
+		}
+	case float64:
+		return value != 0
+	}
+	return def
+}
+
+// listVar reads a list of strings from the job's extra-vars
+func (r *nativeRun) listVar(name string) ([]string, bool) {
+	switch value := r.vars[name].(type) {
+	case []string:
+		return value, true
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// stringVar reads a string extra-var
+func (r *nativeRun) stringVar(name, def string) string {
+	if value, ok := r.vars[name]; ok && value != nil {
+		if s := fmt.Sprint(value); s != "" {
+			return s
+		}
+	}
+	return def
+}
+
+var placeholderPattern = regexp.MustCompile(`\{(volume|data|config|media|host|tz|secret:[a-z0-9_]+)\}`)
+
+// expand substitutes run placeholders in a spec string
+func (r *nativeRun) expand(s string) string {
+	volume := r.stringVar("volume_path", "/mnt/docker")
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		key := match[1 : len(match)-1]
+		switch key {
+		case "volume":
+			return volume
+		case "data":
+			return volume + "/data"
+		case "config":
+			return volume + "/config"
+		case "media":
+			return volume + "/media"
+		case "host":
+			return r.job.Host
+		case "tz":
+			return r.stringVar("timezone", "UTC")
+		}
+		return r.secret(strings.TrimPrefix(key, "secret:"))
+	})
+}
+
+// expandAll expands every string in a list
+func (r *nativeRun) expandAll(values []string) []string {
+	expanded := make([]string, len(values))
+	for i, value := range values {
+		expanded[i] = r.expand(value)
+	}
+	return expanded
+}
+
+// expandFile expands the path and content of a template file
+func (r *nativeRun) expandFile(file templateFile) templateFile {
+	file.Path = r.expand(file.Path)
+	file.Content = r.expand(file.Content)
+	return file
+}
+
+// secret returns a generated secret, creating it on first use in this run
+func (r *nativeRun) secret(name string) string {
+	if value, ok := r.secrets[name]; ok {
+		return value
+	}
+	length := 16
+	if strings.HasSuffix(name, "_token") {
+		length = 32
+	}
+	value := generatePassword(length)
+	r.secrets[name] = value
+	if redactor := redactorFrom(r.ctx); redactor != nil {
+		redactor.add(value)
+	}
+	return value
+}
+
+// generatePassword returns a random alphanumeric string, like Ansible's password lookup
+func generatePassword(length int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			panic(err)
+		}
+		b[i] = chars[n.Int64()]
+	}
+	return string(b)
+}
+
+// shellQuote quotes a string for POSIX sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// shellJoin quotes and joins command arguments
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
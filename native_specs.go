@@ -0,0 +1,39 @@
+package main
+
+// containerSpec describes a container the native executor deploys. String
+// fields may reference {volume}, {data}, {config}, {media}, {host}, {tz}
+// and {secret:<name>} placeholders, which are expanded per run. HostPorts
+// lists the ports a host-network container listens on, as "53" or "53/udp".
+type containerSpec struct {
+	Flag         string            `json:"flag"`
+	Default      bool              `json:"default"`
+	Name         string            `json:"name"`
+	Stack        string            `json:"stack"`
+	Image        string            `json:"image"`
+	Ports        []string          `json:"ports,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	Volumes      []string          `json:"volumes,omitempty"`
+	Devices      []string          `json:"devices,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Sysctls      map[string]string `json:"sysctls,omitempty"`
+	NetworkMode  string            `json:"networkMode,omitempty"`
+	HostPorts    []string          `json:"hostPorts,omitempty"`
+	Restart      string            `json:"restart,omitempty"`
+	Memory       string            `json:"memory,omitempty"`
+	Command      []string          `json:"command,omitempty"`
+	DirOwner     string            `json:"dirOwner,omitempty"`
+	Dirs         []string          `json:"dirs,omitempty"`
+	Files        []templateFile    `json:"files,omitempty"`
+	PostCommands []string          `json:"postCommands,omitempty"`
+	Credentials  *templateFile     `json:"credentials,omitempty"`
+}
+
+// templateFile is a file rendered onto the host before a container starts
+type templateFile struct {
+	Path         string `json:"path"`
+	Content      string `json:"content"`
+	Owner        string `json:"owner,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+	KeepExisting bool   `json:"keepExisting,omitempty"`
+	When         string `json:"when,omitempty"`
+}
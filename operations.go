@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// maxRetryDelay caps the exponential backoff between retries
+const maxRetryDelay = 5 * time.Minute
+
+// OperationsPolicy controls how long remote operations on a host may take and
+// how transient failures are retried
+type OperationsPolicy struct {
+	ConnectTimeoutSeconds int `json:"connectTimeoutSeconds"`
+	// RunTimeoutMinutes limits a whole run; 0 means no limit
+	RunTimeoutMinutes   int `json:"runTimeoutMinutes"`
+	Retries             int `json:"retries"`
+	RetryBackoffSeconds int `json:"retryBackoffSeconds"`
+}
+
+// defaultOperationsPolicy applies to hosts without a saved policy
+var defaultOperationsPolicy = OperationsPolicy{
+	ConnectTimeoutSeconds: 10,
+	RunTimeoutMinutes:     120,
+	Retries:               2,
+	RetryBackoffSeconds:   15,
+}
+
+// transientError is a failure that is expected to clear up if the step is tried again
+type transientError struct {
+	reason  string
+	pattern *regexp.Regexp
+}
+
+// transientErrors lists the failures worth retrying, matched against error output
+var transientErrors = []transientError{
+	{"apt is locked by another process", regexp.MustCompile(`(?i)could not get lock|unable to acquire the dpkg frontend lock|waiting for cache lock`)},
+	{"registry rate limit reached", regexp.MustCompile(`(?i)toomanyrequests|pull rate limit|429 too many requests`)},
+	{"temporary network failure", regexp.MustCompile(`(?i)temporary failure in name resolution|connection reset by peer|connection refused|tls handshake timeout|i/o timeout|connection timed out|operation timed out`)},
+}
+
+// permanentErrorPattern matches failures that look like network errors but will
+// not clear up on their own, such as a wrong password or a changed host key
+var permanentErrorPattern = regexp.MustCompile(`(?i)permission denied|authentication failed|host key verification failed|remote host identification has changed`)
+
+// RunRetry is sent as a runRetry event before a failed step is tried again. Task
+// names the task that failed; Ansible runs retry the whole playbook, not only it.
+type RunRetry struct {
+	Attempt      int    `json:"attempt"`
+	MaxAttempts  int    `json:"maxAttempts"`
+	Task         string `json:"task,omitempty"`
+	Reason       string `json:"reason"`
+	DelaySeconds int    `json:"delaySeconds"`
+}
+
+// transientReason returns why text describes a transient failure, or "" if it does not
+func transientReason(text string) string {
+	if permanentErrorPattern.MatchString(text) {
+		return ""
+	}
+	for _, transient := range transientErrors {
+		if transient.pattern.MatchString(text) {
+			return transient.reason
+		}
+	}
+	return ""
+}
+
+// validate checks that the policy's values are usable
+func (p OperationsPolicy) validate(v *ValidationError) {
+	if p.ConnectTimeoutSeconds < 1 || p.ConnectTimeoutSeconds > 300 {
+		v.add("connectTimeoutSeconds", "must be between 1 and 300")
+	}
+	if p.RunTimeoutMinutes < 0 || p.RunTimeoutMinutes > 24*60 {
+		v.add("runTimeoutMinutes", "must be between 0 and 1440")
+	}
+	if p.Retries < 0 || p.Retries > 10 {
+		v.add("retries", "must be between 0 and 10")
+	}
+	if p.RetryBackoffSeconds < 0 || p.RetryBackoffSeconds > 600 {
+		v.add("retryBackoffSeconds", "must be between 0 and 600")
+	}
+}
+
+// connectTimeout returns the SSH connection timeout
+func (p OperationsPolicy) connectTimeout() time.Duration {
+	if p.ConnectTimeoutSeconds <= 0 {
+		return time.Duration(defaultOperationsPolicy.ConnectTimeoutSeconds) * time.Second
+	}
+	return time.Duration(p.ConnectTimeoutSeconds) * time.Second
+}
+
+// runTimeout returns the limit for a whole run, or 0 for none
+func (p OperationsPolicy) runTimeout() time.Duration {
+	return time.Duration(p.RunTimeoutMinutes) * time.Minute
+}
+
+// retryDelay returns how long to wait before the given retry, doubling each time
+func (p OperationsPolicy) retryDelay(attempt int) time.Duration {
+	delay := time.Duration(p.RetryBackoffSeconds) * time.Second
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// waitToRetry announces a retry and sleeps for its backoff, returning early if ctx ends
+func (p OperationsPolicy) waitToRetry(ctx context.Context, attempt int, task, reason string) error {
+	delay := p.retryDelay(attempt)
+
+	// Output from the failed attempt must reach the frontend before the retry notice
+	outputStreamFrom(ctx).flush()
+	runtime.EventsEmit(ctx, "runRetry", RunRetry{
+		Attempt:      attempt + 1,
+		MaxAttempts:  p.Retries + 1,
+		Task:         task,
+		Reason:       reason,
+		DelaySeconds: int(delay / time.Second),
+	})
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runContextError explains why a run's context ended
+func runContextError(ctx context.Context, policy OperationsPolicy) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("run timed out after %s", policy.runTimeout())
+	}
+	return fmt.Errorf("run cancelled: %v", ctx.Err())
+}
+
+// operationsPolicies stores a policy per host in the user config directory
+type operationsPolicies struct {
+	mu   sync.Mutex
+	path string
+}
+
+// newOperationsPolicies creates a policy store under the user config directory
+func newOperationsPolicies() *operationsPolicies {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &operationsPolicies{path: filepath.Join(dir, serviceName, "operations.json")}
+}
+
+// load reads every saved policy, keyed by host
+func (o *operationsPolicies) load() (map[string]OperationsPolicy, error) {
+	data, err := os.ReadFile(o.path)
+	if os.IsNotExist(err) {
+		return map[string]OperationsPolicy{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operations policies: %v", err)
+	}
+
+	policies := map[string]OperationsPolicy{}
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse operations policies: %v", err)
+	}
+	return policies, nil
+}
+
+// get returns the policy for host, falling back to the default
+func (o *operationsPolicies) get(host string) OperationsPolicy {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	policies, err := o.load()
+	if err != nil {
+		return defaultOperationsPolicy
+	}
+	if policy, ok := policies[host]; ok {
+		return policy
+	}
+	return defaultOperationsPolicy
+}
+
+// set saves the policy for host
+func (o *operationsPolicies) set(host string, policy OperationsPolicy) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	policies, err := o.load()
+	if err != nil {
+		policies = map[string]OperationsPolicy{}
+	}
+	policies[host] = policy
+
+	data, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal operations policies: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	return os.WriteFile(o.path, data, 0600)
+}
+
+// GetOperationsPolicy returns the timeouts and retry policy used for a host
+func (a *App) GetOperationsPolicy(host string) OperationsPolicy {
+	return a.operations.get(host)
+}
+
+// SetOperationsPolicy saves the timeouts and retry policy for a host
+func (a *App) SetOperationsPolicy(host string, policy OperationsPolicy) error {
+	v := &ValidationError{}
+	if !validHost(host) {
+		v.add("host", "must be a host name or IP address")
+	}
+	policy.validate(v)
+	if err := v.err(); err != nil {
+		return err
+	}
+	return a.operations.set(host, policy)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransientReason(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"apt lock", `fatal: [pi]: FAILED! => {"msg": "E: Could not get lock /var/lib/dpkg/lock-frontend"}`, "apt is locked by another process"},
+		{"dpkg frontend lock", "Unable to acquire the dpkg frontend lock", "apt is locked by another process"},
+		{"rate limit", "toomanyrequests: You have reached your pull rate limit", "registry rate limit reached"},
+		{"dns", "Temporary failure in name resolution", "temporary network failure"},
+		{"connection reset", "read tcp 10.0.0.2:22: connection reset by peer", "temporary network failure"},
+		{"connection refused", "ssh: connect to host pi port 22: Connection refused", "temporary network failure"},
+		{"ssh timeout", "Failed to connect to the host via ssh: ssh: connect to host pi port 22: Connection timed out", "temporary network failure"},
+		{"wrong password", "Failed to connect to the host via ssh: pi@pi: Permission denied (publickey,password).", ""},
+		{"host key changed", "Failed to connect to the host via ssh: Host key verification failed.", ""},
+		{"bare ssh failure", "Failed to connect to the host via ssh: kex_exchange_identification: banner line contains invalid characters", ""},
+		{"ordinary failure", `fatal: [pi]: FAILED! => {"msg": "No package matching 'docker-cee' is available"}`, ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transientReason(tt.text); got != tt.want {
+				t.Errorf("transientReason(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := OperationsPolicy{RetryBackoffSeconds: 10}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 20: maxRetryDelay} {
+		if got := policy.retryDelay(attempt); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// outputFlushInterval is how long lines are held before being sent to the frontend
+const outputFlushInterval = 100 * time.Millisecond
+
+// maxPendingOutput is how many lines may be held before a batch is sent early
+const maxPendingOutput = 250
+
+// outputStreamKey is the context key under which a run's output stream is stored
+type outputStreamKey struct{}
+
+// OutputLine is one line of run output as sent to the frontend
+type OutputLine struct {
+	Seq     int64     `json:"seq"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	Raw     string    `json:"raw"`
+}
+
+// outputStream numbers the lines of both output streams in the order they arrive
+// and sends them to the frontend in batches as ansibleOutput events
+type outputStream struct {
+	// emit sends a batch to the frontend
+	emit    func(batch []OutputLine)
+	mu      sync.Mutex
+	seq     int64
+	pending []OutputLine
+	timer   *time.Timer
+	closed  bool
+}
+
+// newOutputStream creates a stream that emits on ctx
+func newOutputStream(ctx context.Context) *outputStream {
+	return &outputStream{emit: func(batch []OutputLine) {
+		runtime.EventsEmit(ctx, "ansibleOutput", batch)
+	}}
+}
+
+// withOutputStream attaches a stream to ctx so every backend's output is batched
+func withOutputStream(ctx context.Context, stream *outputStream) context.Context {
+	return context.WithValue(ctx, outputStreamKey{}, stream)
+}
+
+// outputStreamFrom returns the stream attached to ctx, if any
+func outputStreamFrom(ctx context.Context) *outputStream {
+	if ctx == nil {
+		return nil
+	}
+	stream, _ := ctx.Value(outputStreamKey{}).(*outputStream)
+	return stream
+}
+
+// push queues a line, sending the batch once the interval passes or it grows too large
+func (s *outputStream) push(streamType, message, raw string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.pending = append(s.pending, OutputLine{
+		Seq:     s.seq,
+		Time:    time.Now(),
+		Type:    streamType,
+		Message: message,
+		Raw:     raw,
+	})
+
+	switch {
+	case s.closed || len(s.pending) >= maxPendingOutput:
+		s.flushLocked()
+	case s.timer == nil:
+		s.timer = time.AfterFunc(outputFlushInterval, s.flush)
+	}
+}
+
+// flush sends any pending lines
+func (s *outputStream) flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+// flushLocked sends pending lines; the lock is held while emitting so batches never overtake each other
+func (s *outputStream) flushLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.pending) == 0 {
+		return
+	}
+	s.emit(s.pending)
+	s.pending = nil
+}
+
+// close sends everything still buffered. It must be called before ansibleComplete
+// or ansibleError so the frontend has all output when a run ends; lines pushed
+// afterwards are sent straight away.
+func (s *outputStream) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.flushLocked()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingStream returns a stream that records its batches instead of emitting them
+func recordingStream() (*outputStream, func() [][]OutputLine) {
+	var mu sync.Mutex
+	var batches [][]OutputLine
+	stream := &outputStream{emit: func(batch []OutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, batch)
+	}}
+	return stream, func() [][]OutputLine {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]OutputLine(nil), batches...)
+	}
+}
+
+func TestOutputStreamKeepsArrivalOrder(t *testing.T) {
+	stream, batches := recordingStream()
+
+	stream.push("stdout", "TASK [Install Docker]", "")
+	stream.push("stderr", "warning: apt lock", "")
+	stream.push("stdout", "ok: [pi]", "")
+	stream.close()
+
+	got := batches()
+	if len(got) != 1 || len(got[0]) != 3 {
+		t.Fatalf("batches = %+v, want one batch of three lines", got)
+	}
+	for i, want := range []string{"stdout", "stderr", "stdout"} {
+		if line := got[0][i]; line.Seq != int64(i+1) || line.Type != want {
+			t.Errorf("line %d = seq %d %s, want seq %d %s", i, line.Seq, line.Type, i+1, want)
+		}
+	}
+}
+
+func TestOutputStreamFlushesOnInterval(t *testing.T) {
+	stream, batches := recordingStream()
+
+	stream.push("stdout", "one", "")
+	if got := batches(); len(got) != 0 {
+		t.Fatalf("line sent before the flush interval: %+v", got)
+	}
+
+	deadline := time.Now().Add(10 * outputFlushInterval)
+	for len(batches()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(outputFlushInterval / 4)
+	}
+	if got := batches(); len(got) != 1 || got[0][0].Message != "one" {
+		t.Errorf("batches = %+v, want the line sent after the interval", got)
+	}
+}
+
+func TestOutputStreamSendsFullBatchEarly(t *testing.T) {
+	stream, batches := recordingStream()
+
+	for i := 0; i < maxPendingOutput+1; i++ {
+		stream.push("stdout", "line", "")
+	}
+	got := batches()
+	if len(got) != 1 || len(got[0]) != maxPendingOutput {
+		t.Fatalf("got %d batches, want one full batch of %d lines before the interval", len(got), maxPendingOutput)
+	}
+
+	stream.flush()
+	if got := batches(); len(got) != 2 || len(got[1]) != 1 || got[1][0].Seq != maxPendingOutput+1 {
+		t.Errorf("flush() did not send the remaining line in sequence")
+	}
+}
+
+func TestOutputStreamSendsImmediatelyAfterClose(t *testing.T) {
+	stream, batches := recordingStream()
+
+	stream.close()
+	stream.push("stdout", "late", "")
+	if got := batches(); len(got) != 1 || got[0][0].Message != "late" {
+		t.Errorf("batches = %+v, want the late line sent straight away", got)
+	}
+
+	var missing *outputStream
+	missing.flush()
+	missing.close()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	imageRepoPattern = regexp.MustCompile(`^(?:[A-Za-z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:[._-]+[a-z0-9]+)*(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*$`)
+	imageTagPattern  = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern    = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	timezonePattern  = regexp.MustCompile(`^[A-Za-z]+(?:[/_+-][A-Za-z0-9_+-]+)*$`)
+)
+
+// BindMount is an extra host directory mounted into a container
+type BindMount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// ComponentOverrides customizes how one component is deployed. Ports maps a
+// published host port, written as in the catalog ("80" or "3478/udp"), to the
+// host port to publish it on instead. Everything else applies to the component's
+// main container; zero values keep the catalog's settings. Digest pins the image
+// to an exact registry digest, as recorded in a host's image lock.
+type ComponentOverrides struct {
+	Ports         map[string]int    `json:"ports,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	Image         string            `json:"image,omitempty"`
+	Tag           string            `json:"tag,omitempty"`
+	Digest        string            `json:"digest,omitempty"`
+	Timezone      string            `json:"timezone,omitempty"`
+	PUID          int               `json:"puid,omitempty"`
+	PGID          int               `json:"pgid,omitempty"`
+	MemoryLimitMB int               `json:"memoryLimitMb,omitempty"`
+	Mounts        []BindMount       `json:"mounts,omitempty"`
+}
+
+// splitImage splits an image reference into repository and tag
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// mainContainer returns the container a component is named after, or its first one
+func (c CatalogComponent) mainContainer() containerSpec {
+	for _, spec := range c.containers {
+		if spec.Name == c.ID {
+			return spec
+		}
+	}
+	return c.containers[0]
+}
+
+// templated reports whether a value would be evaluated by Ansible's templating
+func templated(value string) bool {
+	return strings.Contains(value, "{{") || strings.Contains(value, "{%") || strings.Contains(value, "{#")
+}
+
+// validate checks the overrides against the component they apply to
+func (o ComponentOverrides) validate(v *ValidationError, field string, component CatalogComponent) {
+	published := map[string]bool{}
+	for _, spec := range component.containers {
+		for _, mapping := range spec.Ports {
+			key, _ := parsePortMapping(mapping)
+			published[key] = true
+		}
+	}
+	for _, key := range sortedKeys(o.Ports) {
+		if !published[key] {
+			v.add(field+".ports."+key, "%s does not publish port %s", component.Name, key)
+		} else if port := o.Ports[key]; port < 1 || port > 65535 {
+			v.add(field+".ports."+key, "must be between 1 and 65535")
+		}
+	}
+
+	for _, name := range sortedKeys(o.Env) {
+		switch value := o.Env[name]; {
+		case !envNamePattern.MatchString(name):
+			v.add(field+".env."+name, "is not a valid environment variable name")
+		case strings.ContainsAny(value, "\n\r"):
+			v.add(field+".env."+name, "must be a single line")
+		case templated(value):
+			v.add(field+".env."+name, "must not contain {{, {%% or {#")
+		}
+	}
+
+	if o.Image != "" && !imageRepoPattern.MatchString(o.Image) {
+		v.add(field+".image", "must be an image repository such as linuxserver/sonarr or registry.local:5000/sonarr")
+	}
+	if o.Tag != "" && !imageTagPattern.MatchString(o.Tag) {
+		v.add(field+".tag", "must be an image tag such as latest or 4.0.1")
+	}
+	if o.Digest != "" && !digestPattern.MatchString(o.Digest) {
+		v.add(field+".digest", "must be an image digest such as sha256:<64 hex characters>")
+	}
+	if o.Timezone != "" && !timezonePattern.MatchString(o.Timezone) {
+		v.add(field+".timezone", "must be a timezone such as UTC or Europe/London")
+	}
+
+	main := component.mainContainer()
+	_, usesPUID := main.Env["PUID"]
+	if o.PUID != 0 || o.PGID != 0 {
+		switch {
+		case !usesPUID:
+			v.add(field+".puid", "%s does not take a PUID or PGID", component.Name)
+		case o.PUID < 0 || o.PGID < 0:
+			v.add(field+".puid", "must not be negative")
+		}
+	}
+
+	if o.MemoryLimitMB != 0 && (o.MemoryLimitMB < 32 || o.MemoryLimitMB > 65536) {
+		v.add(field+".memoryLimitMb", "must be between 32 and 65536")
+	}
+
+	for i, mount := range o.Mounts {
+		mountField := fmt.Sprintf("%s.mounts[%d]", field, i)
+		for _, p := range []struct{ name, value string }{{"source", mount.Source}, {"target", mount.Target}} {
+			switch {
+			case !strings.HasPrefix(p.value, "/"):
+				v.add(mountField+"."+p.name, "must be an absolute path")
+			case path.Clean(p.value) != p.value || strings.ContainsAny(p.value, ":,\n\r") || templated(p.value):
+				v.add(mountField+"."+p.name, "must be a clean path without ':' or ','")
+			}
+		}
+		if mount.Source == "/" {
+			v.add(mountField+".source", "must not be the root directory")
+		}
+	}
+}
+
+// overrideVars returns the extra-vars that apply a component's overrides to its
+// containers, named after the container as the stack playbooks expect
+func (c CatalogComponent) overrideVars(overrides ComponentOverrides) map[string]interface{} {
+	vars := map[string]interface{}{}
+	if len(overrides.Ports) > 0 {
+		for _, spec := range c.containers {
+			if len(spec.Ports) > 0 {
+				vars[portsVar(spec.Name)] = overridePorts(spec, overrides)
+			}
+		}
+	}
+
+	main := c.mainContainer()
+	prefix := strings.ReplaceAll(main.Name, "-", "_")
+
+	if overrides.Image != "" || overrides.Tag != "" || overrides.Digest != "" {
+		repository, tag := splitImage(main.Image)
+		if overrides.Image != "" {
+			repository = overrides.Image
+		}
+		if overrides.Tag != "" {
+			tag = overrides.Tag
+		}
+		image := repository + ":" + tag
+		if overrides.Digest != "" {
+			image += "@" + overrides.Digest
+		}
+		vars[prefix+"_image"] = image
+	}
+
+	env := map[string]interface{}{}
+	for name, value := range overrides.Env {
+		env[name] = value
+	}
+	if overrides.Timezone != "" {
+		env["TZ"] = overrides.Timezone
+	}
+	if overrides.PUID != 0 {
+		env["PUID"] = strconv.Itoa(overrides.PUID)
+	}
+	if overrides.PGID != 0 {
+		env["PGID"] = strconv.Itoa(overrides.PGID)
+	}
+	if len(env) > 0 {
+		vars[prefix+"_env"] = env
+	}
+
+	if overrides.MemoryLimitMB != 0 {
+		vars[prefix+"_memory"] = fmt.Sprintf("%dm", overrides.MemoryLimitMB)
+	}
+
+	if len(overrides.Mounts) > 0 {
+		mounts := make([]interface{}, 0, len(overrides.Mounts))
+		for _, mount := range overrides.Mounts {
+			mounts = append(mounts, map[string]interface{}{
+				"type":      "bind",
+				"source":    mount.Source,
+				"target":    mount.Target,
+				"read_only": mount.ReadOnly,
+			})
+		}
+		vars[prefix+"_mounts"] = mounts
+	}
+	return vars
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// catalogComponent returns a built-in component, failing the test if it is missing
+func catalogComponent(t *testing.T, id string) CatalogComponent {
+	t.Helper()
+	component, ok := defaultCatalog.component(id)
+	if !ok {
+		t.Fatalf("catalog has no %s component", id)
+	}
+	return component
+}
+
+func TestComponentOverridesValidate(t *testing.T) {
+	sonarr := catalogComponent(t, "sonarr")
+	grafana := catalogComponent(t, "grafana")
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		name      string
+		component CatalogComponent
+		overrides ComponentOverrides
+		field     string
+	}{
+		{"empty", sonarr, ComponentOverrides{}, ""},
+		{"everything valid", sonarr, ComponentOverrides{
+			Ports:         map[string]int{"8989": 18989},
+			Env:           map[string]string{"UMASK": "002"},
+			Image:         "registry.local:5000/linuxserver/sonarr",
+			Tag:           "4.0.1",
+			Digest:        digest,
+			Timezone:      "Europe/London",
+			PUID:          1001,
+			PGID:          1001,
+			MemoryLimitMB: 512,
+			Mounts:        []BindMount{{Source: "/srv/tv", Target: "/tv2", ReadOnly: true}},
+		}, ""},
+		{"unpublished port", sonarr, ComponentOverrides{Ports: map[string]int{"80": 8080}}, "sonarr.ports.80"},
+		{"port out of range", sonarr, ComponentOverrides{Ports: map[string]int{"8989": 70000}}, "sonarr.ports.8989"},
+		{"bad env name", sonarr, ComponentOverrides{Env: map[string]string{"1X": "y"}}, "sonarr.env.1X"},
+		{"multi-line env", sonarr, ComponentOverrides{Env: map[string]string{"X": "a\nb"}}, "sonarr.env.X"},
+		{"templated env", sonarr, ComponentOverrides{Env: map[string]string{"X": "{{ x }}"}}, "sonarr.env.X"},
+		{"bad image", sonarr, ComponentOverrides{Image: "Linuxserver/Sonarr"}, "sonarr.image"},
+		{"bad tag", sonarr, ComponentOverrides{Tag: "-latest"}, "sonarr.tag"},
+		{"bad digest", sonarr, ComponentOverrides{Digest: "sha256:abc"}, "sonarr.digest"},
+		{"bad timezone", sonarr, ComponentOverrides{Timezone: "Europe/London; rm"}, "sonarr.timezone"},
+		{"puid without support", grafana, ComponentOverrides{PUID: 1000}, "grafana.puid"},
+		{"negative puid", sonarr, ComponentOverrides{PGID: -1}, "sonarr.puid"},
+		{"memory too small", sonarr, ComponentOverrides{MemoryLimitMB: 16}, "sonarr.memoryLimitMb"},
+		{"relative mount", sonarr, ComponentOverrides{Mounts: []BindMount{{Source: "tv", Target: "/tv"}}}, "sonarr.mounts[0].source"},
+		{"unclean mount", sonarr, ComponentOverrides{Mounts: []BindMount{{Source: "/srv/../etc", Target: "/etc"}}}, "sonarr.mounts[0].source"},
+		{"mount with colon", sonarr, ComponentOverrides{Mounts: []BindMount{{Source: "/srv", Target: "/tv:rw"}}}, "sonarr.mounts[0].target"},
+		{"root mount", sonarr, ComponentOverrides{Mounts: []BindMount{{Source: "/", Target: "/host"}}}, "sonarr.mounts[0].source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &ValidationError{}
+			tt.overrides.validate(v, tt.component.ID, tt.component)
+			if tt.field == "" {
+				if err := v.err(); err != nil {
+					t.Fatalf("validate() = %v, want no errors", err)
+				}
+				return
+			}
+			found := false
+			for _, field := range v.Fields {
+				found = found || field.Field == tt.field
+			}
+			if !found {
+				t.Errorf("validate() fields = %+v, want an error on %s", v.Fields, tt.field)
+			}
+		})
+	}
+}
+
+func TestOverrideVars(t *testing.T) {
+	sonarr := catalogComponent(t, "sonarr")
+
+	if vars := sonarr.overrideVars(ComponentOverrides{}); len(vars) != 0 {
+		t.Errorf("overrideVars() without overrides = %v, want none", vars)
+	}
+
+	got := sonarr.overrideVars(ComponentOverrides{
+		Ports:         map[string]int{"8989": 18989},
+		Env:           map[string]string{"UMASK": "002"},
+		Tag:           "4.0.1",
+		Digest:        "sha256:abc",
+		Timezone:      "UTC",
+		PUID:          1001,
+		MemoryLimitMB: 512,
+		Mounts:        []BindMount{{Source: "/srv/tv", Target: "/tv2", ReadOnly: true}},
+	})
+	want := map[string]interface{}{
+		"sonarr_ports":  []string{"18989:8989"},
+		"sonarr_image":  "linuxserver/sonarr:4.0.1@sha256:abc",
+		"sonarr_env":    map[string]interface{}{"UMASK": "002", "TZ": "UTC", "PUID": "1001"},
+		"sonarr_memory": "512m",
+		"sonarr_mounts": []interface{}{map[string]interface{}{"type": "bind", "source": "/srv/tv", "target": "/tv2", "read_only": true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overrideVars() = %v\nwant %v", got, want)
+	}
+}
+
+func TestOverrideVarsImageRepository(t *testing.T) {
+	got := catalogComponent(t, "grafana").overrideVars(ComponentOverrides{Image: "registry.local:5000/grafana"})
+	if image := got["grafana_image"]; image != "registry.local:5000/grafana:latest" {
+		t.Errorf("grafana_image = %v, want the catalog tag on the new repository", image)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanStep is one component of a deployment, in the order it is deployed
+type PlanStep struct {
+	Component  string   `json:"component"`
+	Name       string   `json:"name"`
+	Stack      string   `json:"stack"`
+	Containers []string `json:"containers"`
+	RAMMB      int      `json:"ramMb"`
+	// Reason is "selected", or names the component that required it
+	Reason string `json:"reason"`
+}
+
+// DeploymentPlan is what a stack deployment will do, worked out before it runs.
+// Config is the selection with every required component switched on.
+type DeploymentPlan struct {
+	Steps     []PlanStep  `json:"steps"`
+	Included  []string    `json:"included"`
+	Warnings  []string    `json:"warnings"`
+	HostRAMMB int         `json:"hostRamMb"`
+	HostArch  string      `json:"hostArch,omitempty"`
+	RAMMB     int         `json:"ramMb"`
+	Config    StackConfig `json:"config"`
+}
+
+// resolveDeployment switches on the dependencies of every selected component and
+// rejects selections whose dependencies live in an unselected stack, that hit a
+// conflict rule or that have no image for the host's architecture. hostRAMMB is
+// the host's memory, or 0 if unknown; hostArch is its dpkg architecture, or "".
+func resolveDeployment(config StackConfig, hostRAMMB int, hostArch string) (DeploymentPlan, error) {
+	plan := DeploymentPlan{
+		Steps:     []PlanStep{},
+		Included:  []string{},
+		Warnings:  []string{},
+		HostRAMMB: hostRAMMB,
+		HostArch:  hostArch,
+		Config:    StackConfig{Stacks: map[string]bool{}, Components: map[string]bool{}, Overrides: config.Overrides},
+	}
+	for id, selected := range config.Stacks {
+		plan.Config.Stacks[id] = selected
+	}
+
+	stackSelected := func(id string) bool {
+		stack, ok := currentCatalog().stack(id)
+		return ok && (stack.Flag == "" || config.Stacks[id])
+	}
+
+	enabled := map[string]bool{}
+	reasons := map[string]string{}
+	for _, component := range currentCatalog().Components {
+		selected, ok := config.Components[component.Flag]
+		if !ok {
+			selected = component.Default
+		}
+		if selected && stackSelected(component.Stack) {
+			enabled[component.ID] = true
+			reasons[component.ID] = "selected"
+		}
+	}
+
+	// Dependencies are declared before the components that need them, so walking
+	// the catalog backwards also picks up the dependencies of included components
+	v := &ValidationError{}
+	for i := len(currentCatalog().Components) - 1; i >= 0; i-- {
+		component := currentCatalog().Components[i]
+		if !enabled[component.ID] {
+			continue
+		}
+		for _, id := range component.Dependencies {
+			if enabled[id] {
+				continue
+			}
+			dependency, _ := currentCatalog().component(id)
+			if !stackSelected(dependency.Stack) {
+				stack, _ := currentCatalog().stack(dependency.Stack)
+				v.add("components."+component.Flag, "%s needs %s from the %s, which is not selected", component.Name, dependency.Name, stack.Name)
+				continue
+			}
+			enabled[id] = true
+			reasons[id] = "required by " + component.Name
+			plan.Included = append(plan.Included, fmt.Sprintf("%s was added because %s needs it", dependency.Name, component.Name))
+		}
+	}
+
+	for _, component := range currentCatalog().Components {
+		if !enabled[component.ID] {
+			continue
+		}
+		if supported := component.Architectures; len(supported) > 0 {
+			switch {
+			case hostArch != "" && !containsString(supported, hostArch):
+				v.add("components."+component.Flag, "%s has no image for %s hosts, only for %s", component.Name, hostArch, strings.Join(supported, ", "))
+			case hostArch == "" && !containsAll(supported, customArchitectures):
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s only runs on %s hosts; the host's architecture could not be checked", component.Name, strings.Join(supported, ", ")))
+			}
+		}
+		for _, conflict := range component.Conflicts {
+			if !enabled[conflict.Component] {
+				continue
+			}
+			other, _ := currentCatalog().component(conflict.Component)
+			switch {
+			case conflict.HostRAMBelowMB == 0 || (hostRAMMB > 0 && hostRAMMB < conflict.HostRAMBelowMB):
+				v.add("components."+component.Flag, "cannot be deployed with %s: %s", other.Name, conflict.Reason)
+			case hostRAMMB == 0:
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s and %s need at least %dMB of RAM together; the host's memory could not be checked", component.Name, other.Name, conflict.HostRAMBelowMB))
+			}
+		}
+	}
+	if err := v.err(); err != nil {
+		return plan, err
+	}
+
+	for _, component := range currentCatalog().Components {
+		plan.Config.Components[component.Flag] = enabled[component.ID]
+		if !enabled[component.ID] {
+			continue
+		}
+		containers := []string{}
+		for _, spec := range component.containers {
+			containers = append(containers, spec.Name)
+		}
+		plan.Steps = append(plan.Steps, PlanStep{
+			Component:  component.ID,
+			Name:       component.Name,
+			Stack:      component.Stack,
+			Containers: containers,
+			RAMMB:      component.RAMMB,
+			Reason:     reasons[component.ID],
+		})
+		plan.RAMMB += component.RAMMB
+	}
+	if hostRAMMB > 0 && plan.RAMMB > hostRAMMB {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("The selected components use about %dMB of RAM but the host has %dMB", plan.RAMMB, hostRAMMB))
+	}
+	return plan, nil
+}
+
+// hostCommand runs one command on the host over a fresh SSH connection and returns its output
+func hostCommand(host, user, password string, timeout time.Duration, command string) (string, error) {
+	client, err := dialSSH(host, user, password, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %v", err)
+	}
+	defer session.Close()
+
+	output, err := session.Output(command)
+	return string(output), err
+}
+
+// hostSudo runs one command as root on the host over a fresh SSH connection
+func hostSudo(host, user, password string, timeout time.Duration, command string) (string, error) {
+	client, err := dialSSH(host, user, password, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	r := &nativeRun{ctx: context.Background(), client: client, job: PlaybookJob{Host: host, User: user, Password: password}}
+	return r.sudo(command)
+}
+
+// hostMemoryMB reads the host's total memory in megabytes
+func hostMemoryMB(host, user, password string, timeout time.Duration) (int, error) {
+	output, err := hostCommand(host, user, password, timeout, "awk '/^MemTotal:/ {print int($2 / 1024)}' /proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory: %v", err)
+	}
+	memory, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse memory %q: %v", strings.TrimSpace(output), err)
+	}
+	return memory, nil
+}
+
+// hostArchitecture reads the host's dpkg architecture, such as arm64 or armhf
+func hostArchitecture(host, user, password string, timeout time.Duration) (string, error) {
+	output, err := hostCommand(host, user, password, timeout, "dpkg --print-architecture")
+	if err != nil {
+		return "", fmt.Errorf("failed to read architecture: %v", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// PlanDeployment validates a stack selection and returns the components it would
+// deploy, in order, with the dependencies it adds and any warnings. Conflict rules
+// that depend on memory, and components that only run on some architectures, are
+// reported as warnings when the host cannot be read.
+func (a *App) PlanDeployment(host, user, password, volumePath string, config StackConfig) (DeploymentPlan, error) {
+	if err := validateStackInputs(host, user, volumePath, config); err != nil {
+		return DeploymentPlan{}, err
+	}
+
+	timeout := a.operations.get(host).connectTimeout()
+	memory, err := hostMemoryMB(host, user, password, timeout)
+	if err != nil {
+		log.Printf("Could not read memory of %s: %v", host, err)
+	}
+	arch, err := hostArchitecture(host, user, password, timeout)
+	if err != nil {
+		log.Printf("Could not read architecture of %s: %v", host, err)
+	}
+	return resolveDeployment(config, memory, arch)
+}
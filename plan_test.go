@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// fieldErrors returns the fields of a ValidationError, or nil for any other error
+func fieldErrors(err error) []string {
+	var fields []string
+	if v, ok := err.(*ValidationError); ok {
+		for _, field := range v.Fields {
+			fields = append(fields, field.Field)
+		}
+	}
+	return fields
+}
+
+func TestResolveDeploymentArchitectures(t *testing.T) {
+	activeCatalog.Store(nil)
+	unifi := StackConfig{
+		Stacks:     map[string]bool{"network": true},
+		Components: map[string]bool{"deploy_unifi": true},
+	}
+	grafanaOnly := StackConfig{
+		Stacks:     map[string]bool{"iot": true},
+		Components: map[string]bool{"deploy_influxdb": false, "deploy_mosquitto": false, "deploy_home_assistant": false, "deploy_grafana": true},
+	}
+
+	tests := []struct {
+		name    string
+		config  StackConfig
+		arch    string
+		fields  []string
+		warning string
+	}{
+		{"supported", unifi, "arm64", nil, ""},
+		{"unsupported", unifi, "armhf", []string{"components.deploy_unifi"}, ""},
+		{"unsupported dependency", grafanaOnly, "armhf", []string{"components.deploy_influxdb"}, ""},
+		{"unknown architecture", unifi, "", nil, "UniFi Controller only runs on arm64, amd64 hosts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := resolveDeployment(tt.config, 0, tt.arch)
+			if fields := fieldErrors(err); strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("resolveDeployment() error = %v, want errors on %v", err, tt.fields)
+			}
+			if tt.fields != nil {
+				return
+			}
+			found := tt.warning == ""
+			for _, warning := range plan.Warnings {
+				found = found || strings.HasPrefix(warning, tt.warning)
+			}
+			if !found {
+				t.Errorf("warnings = %v, want one starting %q", plan.Warnings, tt.warning)
+			}
+			if plan.HostArch != tt.arch {
+				t.Errorf("HostArch = %q, want %q", plan.HostArch, tt.arch)
+			}
+		})
+	}
+}
+
+func TestResolveDeployment(t *testing.T) {
+	activeCatalog.Store(nil)
+
+	tests := []struct {
+		name     string
+		config   StackConfig
+		ramMB    int
+		want     []string
+		included int
+		fields   []string
+		warnings int
+	}{
+		{
+			name:   "unselected stack deploys nothing",
+			config: StackConfig{Components: map[string]bool{"deploy_portainer": false, "deploy_pihole": true}},
+			want:   nil,
+		},
+		{
+			name:   "defaults of a selected stack",
+			config: StackConfig{Stacks: map[string]bool{"network": true}, Components: map[string]bool{"deploy_portainer": false}},
+			want:   []string{"pihole", "nginx-proxy-manager", "heimdall"},
+		},
+		{
+			name: "dependencies added and ordered first",
+			config: StackConfig{
+				Stacks:     map[string]bool{"media": true},
+				Components: map[string]bool{"deploy_portainer": false, "deploy_jellyfin": false, "deploy_nextcloud": false, "deploy_sonarr": true},
+			},
+			want:     []string{"transmission", "jackett", "sonarr"},
+			included: 2,
+		},
+		{
+			name: "conflict on a small host",
+			config: StackConfig{
+				Stacks:     map[string]bool{"media": true},
+				Components: map[string]bool{"deploy_plex": true},
+			},
+			ramMB:  1024,
+			fields: []string{"components.deploy_plex"},
+		},
+		{
+			name: "conflict allowed on a large host",
+			config: StackConfig{
+				Stacks:     map[string]bool{"media": true},
+				Components: map[string]bool{"deploy_portainer": false, "deploy_nextcloud": false, "deploy_plex": true},
+			},
+			ramMB: 4096,
+			want:  []string{"jellyfin", "plex"},
+		},
+		{
+			name: "conflict with unknown memory",
+			config: StackConfig{
+				Stacks:     map[string]bool{"media": true},
+				Components: map[string]bool{"deploy_portainer": false, "deploy_nextcloud": false, "deploy_plex": true},
+			},
+			want:     []string{"jellyfin", "plex"},
+			warnings: 1,
+		},
+		{
+			name: "memory over the host's",
+			config: StackConfig{
+				Stacks:     map[string]bool{"media": true},
+				Components: map[string]bool{"deploy_portainer": false, "deploy_nextcloud": false},
+			},
+			ramMB:    256,
+			want:     []string{"jellyfin"},
+			warnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := resolveDeployment(tt.config, tt.ramMB, "arm64")
+			if fields := fieldErrors(err); strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("resolveDeployment() error = %v, want errors on %v", err, tt.fields)
+			}
+			if tt.fields != nil {
+				return
+			}
+
+			var steps []string
+			for _, step := range plan.Steps {
+				steps = append(steps, step.Component)
+			}
+			if strings.Join(steps, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("steps = %v, want %v", steps, tt.want)
+			}
+			if len(plan.Included) != tt.included {
+				t.Errorf("included = %v, want %d entries", plan.Included, tt.included)
+			}
+			if len(plan.Warnings) != tt.warnings {
+				t.Errorf("warnings = %v, want %d", plan.Warnings, tt.warnings)
+			}
+			for _, step := range plan.Steps {
+				component, _ := currentCatalog().component(step.Component)
+				if !plan.Config.Components[component.Flag] {
+					t.Errorf("plan config does not enable %s", component.Flag)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//go:embed wails.json
+var wailsConfig []byte
+
+// appVersion is the application version stamped into playbook work directories.
+// It is read from wails.json, which the release workflow stamps before building.
+var appVersion = readAppVersion(wailsConfig)
+
+// workspaceOwnerFile records the process that created a workspace
+const workspaceOwnerFile = "OWNER"
+
+// staleWorkspaceAge is how old an unowned workspace must be before it is swept
+const staleWorkspaceAge = time.Hour
+
+// playbookOverrideEnv names the environment variable that points at a custom playbook directory
+const playbookOverrideEnv = "DOCKERIZATHINGINATOR_PLAYBOOKS"
+
+var (
+	playbookVersionOnce  sync.Once
+	playbookVersionValue string
+)
+
+// readAppVersion returns the productVersion of a wails.json, or "dev" if it has none
+func readAppVersion(data []byte) string {
+	var config struct {
+		Info struct {
+			ProductVersion string `json:"productVersion"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil || config.Info.ProductVersion == "" {
+		return "dev"
+	}
+	return config.Info.ProductVersion
+}
+
+// playbookVersion identifies the embedded ansible tree by app version and content hash
+func playbookVersion() string {
+	playbookVersionOnce.Do(func() {
+		hash := sha256.New()
+		fs.WalkDir(ansibleAssets, "ansible", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := ansibleAssets.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "%s\x00%d\x00", path, len(data))
+			hash.Write(data)
+			return nil
+		})
+		playbookVersionValue = fmt.Sprintf("%s-%s", appVersion, hex.EncodeToString(hash.Sum(nil))[:12])
+	})
+	return playbookVersionValue
+}
+
+// playbookOverrideDir returns the user's custom playbook directory, or "" if none exists
+func playbookOverrideDir() string {
+	dir := os.Getenv(playbookOverrideEnv)
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(configDir, serviceName, "playbooks")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// PlaybookWorkspace is a private, per-run copy of the ansible tree
+type PlaybookWorkspace struct {
+	root string
+}
+
+// NewPlaybookWorkspace materialises the embedded ansible tree, plus any user overrides, into a fresh directory
+func NewPlaybookWorkspace() (*PlaybookWorkspace, error) {
+	root, err := os.MkdirTemp("", fmt.Sprintf("%s-%s-", serviceName, playbookVersion()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playbook work directory: %v", err)
+	}
+	ws := &PlaybookWorkspace{root: root}
+
+	// Record the owner first so a crash at any later point leaves a sweepable directory
+	if err := os.WriteFile(filepath.Join(root, workspaceOwnerFile), []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
+		ws.Cleanup()
+		return nil, fmt.Errorf("failed to create playbook work directory: %v", err)
+	}
+
+	if err := ws.extract(); err != nil {
+		ws.Cleanup()
+		return nil, err
+	}
+
+	if override := playbookOverrideDir(); override != "" {
+		if err := copyTree(os.DirFS(override), ".", ws.PlaybooksDir()); err != nil {
+			ws.Cleanup()
+			return nil, fmt.Errorf("failed to apply playbook overrides from %s: %v", override, err)
+		}
+	}
+
+	if custom := customPlaybooksDir(); custom != "" {
+		if info, err := os.Stat(custom); err == nil && info.IsDir() {
+			if err := copyTree(os.DirFS(custom), ".", ws.CustomDir()); err != nil {
+				ws.Cleanup()
+				return nil, fmt.Errorf("failed to copy custom playbooks from %s: %v", custom, err)
+			}
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "VERSION"), []byte(playbookVersion()+"\n"), 0600); err != nil {
+		ws.Cleanup()
+		return nil, fmt.Errorf("failed to write playbook version: %v", err)
+	}
+
+	return ws, nil
+}
+
+// extract writes the embedded ansible tree into the workspace
+func (ws *PlaybookWorkspace) extract() error {
+	if err := copyTree(ansibleAssets, "ansible", ws.AnsibleDir()); err != nil {
+		return fmt.Errorf("failed to extract embedded playbooks: %v", err)
+	}
+	return nil
+}
+
+// Root returns the workspace directory
+func (ws *PlaybookWorkspace) Root() string {
+	return ws.root
+}
+
+// AnsibleDir returns the materialised ansible directory
+func (ws *PlaybookWorkspace) AnsibleDir() string {
+	return filepath.Join(ws.root, "ansible")
+}
+
+// PlaybooksDir returns the materialised playbooks directory
+func (ws *PlaybookWorkspace) PlaybooksDir() string {
+	return filepath.Join(ws.AnsibleDir(), "playbooks")
+}
+
+// PlaybookPath resolves a validated playbook name inside the workspace
+func (ws *PlaybookWorkspace) PlaybookPath(playbook string) (string, error) {
+	if err := validatePlaybookName(playbook); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(ws.PlaybooksDir(), playbook)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("playbook file not found: %s", playbook)
+	}
+	return path, nil
+}
+
+// CustomDir returns the materialised custom playbook directory
+func (ws *PlaybookWorkspace) CustomDir() string {
+	return filepath.Join(ws.root, "custom")
+}
+
+// CustomPlaybookPath resolves a validated custom playbook name inside the workspace
+func (ws *PlaybookWorkspace) CustomPlaybookPath(playbook string) (string, error) {
+	if err := validatePlaybookName(playbook); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(ws.CustomDir(), playbook)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("custom playbook file not found: %s", playbook)
+	}
+	return path, nil
+}
+
+// SecretsDir returns the directory for files that must be shredded after the run
+func (ws *PlaybookWorkspace) SecretsDir() string {
+	return filepath.Join(ws.root, "secrets")
+}
+
+// WriteSecret writes a file into the secrets directory, where Cleanup will shred it
+func (ws *PlaybookWorkspace) WriteSecret(name string, data []byte, perm os.FileMode) (string, error) {
+	if err := os.MkdirAll(ws.SecretsDir(), 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(ws.SecretsDir(), name)
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Cleanup shreds any secrets and removes the workspace
+func (ws *PlaybookWorkspace) Cleanup() {
+	if ws.root != "" {
+		removeWorkspace(ws.root)
+	}
+}
+
+// removeWorkspace overwrites everything in a workspace's secrets directory before deleting it
+func removeWorkspace(root string) {
+	filepath.WalkDir(filepath.Join(root, "secrets"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			shredFile(path)
+		}
+		return nil
+	})
+	os.RemoveAll(root)
+}
+
+// shredFile overwrites a file with zeros and removes it
+func shredFile(path string) {
+	if file, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if info, err := file.Stat(); err == nil {
+			file.Write(make([]byte, info.Size()))
+			file.Sync()
+		}
+		file.Close()
+	}
+	os.Remove(path)
+}
+
+// sweepWorkspaces removes workspaces left behind by processes that crashed or were
+// killed before their cleanup ran. With includeOwn set, this process's workspaces
+// are removed as well.
+func sweepWorkspaces(includeOwn bool) {
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), serviceName+"-*"))
+	if err != nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		info, err := os.Lstat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, workspaceOwnerFile))
+		if err != nil {
+			// Unowned directories are either mid-creation or from an older version
+			if time.Since(info.ModTime()) > staleWorkspaceAge {
+				removeWorkspace(dir)
+			}
+			continue
+		}
+
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || (pid == os.Getpid() && includeOwn) || (pid != os.Getpid() && !processAlive(pid)) {
+			removeWorkspace(dir)
+		}
+	}
+}
+
+// processAlive reports whether a process with the given pid is still running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess already fails for exited processes
+	if goruntime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+// copyTree copies every regular file under src in fsys into dest
+func copyTree(fsys fs.FS, src, dest string) error {
+	return fs.WalkDir(fsys, src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0600)
+	})
+}
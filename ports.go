@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortBinding is a host port a component will listen on
+type PortBinding struct {
+	Component string `json:"component"`
+	Container string `json:"container"`
+	HostPort  int    `json:"hostPort"`
+	Protocol  string `json:"protocol"`
+	// Target is the container port, or "host" for host-network containers
+	Target string `json:"target"`
+	// DefaultPort is the catalog's host port; Fixed bindings cannot be moved
+	DefaultPort int  `json:"defaultPort"`
+	Fixed       bool `json:"fixed"`
+}
+
+// PortConflict is a host port wanted by more than one component or already in use
+type PortConflict struct {
+	Port       int      `json:"port"`
+	Protocol   string   `json:"protocol"`
+	Holders    []string `json:"holders"`
+	Resolution string   `json:"resolution"`
+	Resolved   bool     `json:"resolved"`
+}
+
+// PortPlan is the final host port map of a deployment. Config carries the
+// selection with the proposed port overrides applied.
+type PortPlan struct {
+	Bindings  []PortBinding  `json:"bindings"`
+	Conflicts []PortConflict `json:"conflicts"`
+	Config    StackConfig    `json:"config"`
+}
+
+// publishedPortPattern matches a published port in docker ps output, like 0.0.0.0:80->80/tcp
+var publishedPortPattern = regexp.MustCompile(`:(\d+)->\d+/(tcp|udp)`)
+
+// ssProcessPattern matches the process name in ss output, like users:(("sshd",pid=1,fd=3))
+var ssProcessPattern = regexp.MustCompile(`users:\(\("([^"]+)"`)
+
+// portKey names a host port and protocol as the catalog writes them
+func portKey(port int, protocol string) string {
+	if protocol == "tcp" {
+		return strconv.Itoa(port)
+	}
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
+
+// parsePortKey splits "53" or "53/udp" into port and protocol
+func parsePortKey(key string) (int, string, error) {
+	port, protocol, found := strings.Cut(key, "/")
+	if !found {
+		protocol = "tcp"
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 || (protocol != "tcp" && protocol != "udp") {
+		return 0, "", fmt.Errorf("invalid port %q", key)
+	}
+	return number, protocol, nil
+}
+
+// parsePortMapping splits a published port like "8090:80" or "3478:3478/udp"
+// into its host port key and container port
+func parsePortMapping(mapping string) (string, string) {
+	host, target, found := strings.Cut(mapping, ":")
+	if !found {
+		return mapping, mapping
+	}
+	if _, protocol, ok := strings.Cut(target, "/"); ok {
+		return host + "/" + protocol, target
+	}
+	return host, target
+}
+
+// portsVar is the extra-var holding a container's published ports
+func portsVar(container string) string {
+	return strings.ReplaceAll(container, "-", "_") + "_ports"
+}
+
+// overridePorts returns a container's published ports with the overrides applied
+func overridePorts(spec containerSpec, overrides ComponentOverrides) []string {
+	ports := make([]string, 0, len(spec.Ports))
+	for _, mapping := range spec.Ports {
+		key, target := parsePortMapping(mapping)
+		if port, ok := overrides.Ports[key]; ok {
+			mapping = fmt.Sprintf("%d:%s", port, target)
+		}
+		ports = append(ports, mapping)
+	}
+	return ports
+}
+
+// componentBindings lists the host ports a component listens on, overrides applied
+func componentBindings(component CatalogComponent, overrides ComponentOverrides) []PortBinding {
+	var bindings []PortBinding
+	for _, spec := range component.containers {
+		for _, key := range spec.HostPorts {
+			port, protocol, err := parsePortKey(key)
+			if err != nil {
+				continue
+			}
+			bindings = append(bindings, PortBinding{
+				Component:   component.ID,
+				Container:   spec.Name,
+				HostPort:    port,
+				Protocol:    protocol,
+				Target:      "host",
+				DefaultPort: port,
+				Fixed:       true,
+			})
+		}
+		for _, mapping := range spec.Ports {
+			key, target := parsePortMapping(mapping)
+			port, protocol, err := parsePortKey(key)
+			if err != nil {
+				continue
+			}
+			binding := PortBinding{
+				Component:   component.ID,
+				Container:   spec.Name,
+				HostPort:    port,
+				Protocol:    protocol,
+				Target:      target,
+				DefaultPort: port,
+			}
+			if override, ok := overrides.Ports[key]; ok {
+				binding.HostPort = override
+			}
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings
+}
+
+// parseListeningPorts reads ss -ltnup output into the process holding each port
+func parseListeningPorts(output string) map[string]string {
+	listeners := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || (fields[0] != "tcp" && fields[0] != "udp") {
+			continue
+		}
+		address := fields[4]
+		port, err := strconv.Atoi(address[strings.LastIndex(address, ":")+1:])
+		if err != nil {
+			continue
+		}
+		process := "a host process"
+		if match := ssProcessPattern.FindStringSubmatch(line); match != nil {
+			process = match[1]
+		}
+		listeners[portKey(port, fields[0])] = process
+	}
+	return listeners
+}
+
+// parsePublishedPorts reads docker ps output of "name<TAB>ports" lines into the
+// container publishing each port, and the names of every running container
+func parsePublishedPorts(output string) (map[string]string, map[string]bool) {
+	published, running := map[string]string{}, map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		name, ports, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if name == "" {
+			continue
+		}
+		running[name] = true
+		for _, match := range publishedPortPattern.FindAllStringSubmatch(ports, -1) {
+			port, _ := strconv.Atoi(match[1])
+			published[portKey(port, match[2])] = name
+		}
+	}
+	return published, running
+}
+
+// nextFreePort proposes a host port for a binding that has to move: privileged
+// ports move to the 8000 range, others to the next free port above them
+func nextFreePort(port int, protocol string, taken func(string) bool) int {
+	candidate := port + 1
+	if port < 1024 {
+		candidate = port + 8000
+	}
+	for ; candidate <= 65535; candidate++ {
+		if !taken(portKey(candidate, protocol)) {
+			return candidate
+		}
+	}
+	return 0
+}
+
+// planPorts works out the host port map of a resolved deployment. Ports held on
+// the host by anything other than the containers being deployed count as taken.
+// Fixed bindings claim their ports first; movable ones that collide are moved to
+// the next free port, and the move is recorded as an override in the returned config.
+func planPorts(plan DeploymentPlan, listeners, published map[string]string, running map[string]bool) PortPlan {
+	config := plan.Config
+	overrides := map[string]ComponentOverrides{}
+	for id, override := range config.Overrides {
+		overrides[id] = override
+	}
+
+	var bindings []PortBinding
+	deployed := map[string]bool{}
+	for _, step := range plan.Steps {
+		component, _ := currentCatalog().component(step.Component)
+		bindings = append(bindings, componentBindings(component, overrides[component.ID])...)
+		for _, container := range step.Containers {
+			deployed[container] = true
+		}
+	}
+
+	// Ports held by anything that is not about to be replaced
+	fixedPorts := map[string]string{}
+	for _, binding := range bindings {
+		if binding.Fixed {
+			fixedPorts[portKey(binding.HostPort, binding.Protocol)] = binding.Container
+		}
+	}
+	held := map[string]string{}
+	for key, process := range listeners {
+		if container, ok := published[key]; ok {
+			if !deployed[container] {
+				held[key] = "container " + container
+			}
+			continue
+		}
+		// Host-network containers listen directly; a port they already hold is theirs
+		if container, ok := fixedPorts[key]; ok && running[container] {
+			continue
+		}
+		held[key] = process
+	}
+	for key, container := range published {
+		if _, ok := held[key]; !ok && !deployed[container] {
+			held[key] = "container " + container
+		}
+	}
+
+	order := make([]int, len(bindings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bindings[order[i]].Fixed && !bindings[order[j]].Fixed
+	})
+
+	// Moved ports also avoid the ports other bindings want, so one move does not cause another
+	wanted := map[string]bool{}
+	for _, binding := range bindings {
+		wanted[portKey(binding.HostPort, binding.Protocol)] = true
+	}
+	claimed := map[string]string{}
+	taken := func(key string) bool {
+		_, inUse := claimed[key]
+		_, onHost := held[key]
+		return inUse || onHost
+	}
+	conflicts := []PortConflict{}
+	for _, i := range order {
+		binding := &bindings[i]
+		component, _ := currentCatalog().component(binding.Component)
+		key := portKey(binding.HostPort, binding.Protocol)
+		if !taken(key) {
+			claimed[key] = component.Name
+			continue
+		}
+
+		conflict := PortConflict{Port: binding.HostPort, Protocol: binding.Protocol, Holders: []string{component.Name}}
+		if holder, ok := claimed[key]; ok {
+			conflict.Holders = append(conflict.Holders, holder)
+		}
+		if holder, ok := held[key]; ok {
+			conflict.Holders = append(conflict.Holders, holder)
+		}
+
+		port := 0
+		if !binding.Fixed {
+			port = nextFreePort(binding.HostPort, binding.Protocol, func(key string) bool {
+				return taken(key) || wanted[key]
+			})
+		}
+		if port == 0 {
+			conflict.Resolution = fmt.Sprintf("%s uses host networking and cannot be moved; stop %s or deselect one of them", component.Name, strings.Join(conflict.Holders[1:], " and "))
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+
+		binding.HostPort = port
+		claimed[portKey(port, binding.Protocol)] = component.Name
+		override := overrides[component.ID]
+		ports := map[string]int{}
+		for k, p := range override.Ports {
+			ports[k] = p
+		}
+		ports[portKey(binding.DefaultPort, binding.Protocol)] = port
+		override.Ports = ports
+		overrides[component.ID] = override
+
+		conflict.Resolution = fmt.Sprintf("Publish %s's port %s on %d instead", component.Name, binding.Target, port)
+		conflict.Resolved = true
+		conflicts = append(conflicts, conflict)
+	}
+
+	config.Overrides = overrides
+	return PortPlan{Bindings: bindings, Conflicts: conflicts, Config: config}
+}
+
+// PlanPorts checks the host ports of a stack selection against each other and
+// against the ports already in use on the host, and proposes new host ports for
+// any that collide. Deploying the returned config applies the proposals.
+func (a *App) PlanPorts(host, user, password, volumePath string, config StackConfig) (PortPlan, error) {
+	plan, err := a.PlanDeployment(host, user, password, volumePath, config)
+	if err != nil {
+		return PortPlan{}, err
+	}
+
+	timeout := a.operations.get(host).connectTimeout()
+	sockets, err := hostSudo(host, user, password, timeout, "ss -ltnup")
+	if err != nil {
+		return PortPlan{}, fmt.Errorf("failed to list listening ports: %v", err)
+	}
+	containers, err := hostSudo(host, user, password, timeout, `docker ps --format '{{.Names}}\t{{.Ports}}' 2>/dev/null || true`)
+	if err != nil {
+		return PortPlan{}, fmt.Errorf("failed to list running containers: %v", err)
+	}
+
+	published, running := parsePublishedPorts(containers)
+	return planPorts(plan, parseListeningPorts(sockets), published, running), nil
+}
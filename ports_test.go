@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// portTestPlan builds a resolved plan deploying the given components
+func portTestPlan(t *testing.T, ids ...string) DeploymentPlan {
+	t.Helper()
+	plan := DeploymentPlan{Config: StackConfig{Stacks: map[string]bool{}, Components: map[string]bool{}}}
+	for _, id := range ids {
+		component, ok := currentCatalog().component(id)
+		if !ok {
+			t.Fatalf("unknown component %s", id)
+		}
+		step := PlanStep{Component: id}
+		for _, spec := range component.containers {
+			step.Containers = append(step.Containers, spec.Name)
+		}
+		plan.Steps = append(plan.Steps, step)
+		plan.Config.Components[component.Flag] = true
+	}
+	return plan
+}
+
+func TestParsePortKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		port     int
+		protocol string
+		wantErr  bool
+	}{
+		{"80", 80, "tcp", false},
+		{"53/udp", 53, "udp", false},
+		{"443/tcp", 443, "tcp", false},
+		{"0", 0, "", true},
+		{"65536", 0, "", true},
+		{"53/sctp", 0, "", true},
+		{"http", 0, "", true},
+		{"", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		port, protocol, err := parsePortKey(tt.key)
+		if (err != nil) != tt.wantErr || port != tt.port || protocol != tt.protocol {
+			t.Errorf("parsePortKey(%q) = %d, %q, %v; want %d, %q, error %v", tt.key, port, protocol, err, tt.port, tt.protocol, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePortMapping(t *testing.T) {
+	tests := []struct {
+		mapping string
+		key     string
+		target  string
+	}{
+		{"8090:80", "8090", "80"},
+		{"3478:3478/udp", "3478/udp", "3478/udp"},
+		{"51820/udp", "51820/udp", "51820/udp"},
+	}
+
+	for _, tt := range tests {
+		if key, target := parsePortMapping(tt.mapping); key != tt.key || target != tt.target {
+			t.Errorf("parsePortMapping(%q) = %q, %q; want %q, %q", tt.mapping, key, target, tt.key, tt.target)
+		}
+	}
+}
+
+func TestParseListeningPorts(t *testing.T) {
+	output := strings.Join([]string{
+		"Netid State  Recv-Q Send-Q Local Address:Port  Peer Address:Port Process",
+		`udp   UNCONN 0      0      127.0.0.53%lo:53     0.0.0.0:*         users:(("systemd-resolve",pid=412,fd=13))`,
+		`tcp   LISTEN 0      128    0.0.0.0:22           0.0.0.0:*         users:(("sshd",pid=601,fd=3))`,
+		`tcp   LISTEN 0      4096   [::]:9000            [::]:*`,
+		"tcp   LISTEN 0      128    0.0.0.0:http         0.0.0.0:*",
+		"",
+	}, "\n")
+
+	want := map[string]string{
+		"53/udp": "systemd-resolve",
+		"22":     "sshd",
+		"9000":   "a host process",
+	}
+	if got := parseListeningPorts(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListeningPorts() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePublishedPorts(t *testing.T) {
+	output := "portainer\t0.0.0.0:9000->9000/tcp, :::9000->9000/tcp, 0.0.0.0:9443->9443/tcp\n" +
+		"pihole\t\n" +
+		"unifi-controller\t0.0.0.0:3478->3478/udp\n"
+
+	published, running := parsePublishedPorts(output)
+	wantPublished := map[string]string{"9000": "portainer", "9443": "portainer", "3478/udp": "unifi-controller"}
+	wantRunning := map[string]bool{"portainer": true, "pihole": true, "unifi-controller": true}
+	if !reflect.DeepEqual(published, wantPublished) {
+		t.Errorf("published = %v, want %v", published, wantPublished)
+	}
+	if !reflect.DeepEqual(running, wantRunning) {
+		t.Errorf("running = %v, want %v", running, wantRunning)
+	}
+}
+
+func TestNextFreePort(t *testing.T) {
+	taken := func(key string) bool { return key == "8080" || key == "9001" }
+
+	tests := []struct {
+		port     int
+		protocol string
+		want     int
+	}{
+		{80, "tcp", 8081},
+		{9000, "tcp", 9002},
+		{9000, "udp", 9001},
+		{65535, "tcp", 0},
+	}
+	for _, tt := range tests {
+		if got := nextFreePort(tt.port, tt.protocol, taken); got != tt.want {
+			t.Errorf("nextFreePort(%d, %s) = %d, want %d", tt.port, tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestPlanPortsMovesMovableBinding(t *testing.T) {
+	// Pi-hole uses host networking, so Nginx Proxy Manager gives way on port 80
+	result := planPorts(portTestPlan(t, "pihole", "nginx-proxy-manager"), nil, nil, nil)
+
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("conflicts = %+v, want one", result.Conflicts)
+	}
+	conflict := result.Conflicts[0]
+	if conflict.Port != 80 || !conflict.Resolved {
+		t.Errorf("conflict = %+v, want a resolved conflict on port 80", conflict)
+	}
+	if got := result.Config.Overrides["nginx-proxy-manager"].Ports["80"]; got != 8080 {
+		t.Errorf("nginx-proxy-manager port 80 moved to %d, want 8080", got)
+	}
+	if _, ok := result.Config.Overrides["pihole"]; ok {
+		t.Error("pihole was given an override, but host-network ports cannot move")
+	}
+}
+
+func TestPlanPortsHostListener(t *testing.T) {
+	listeners := map[string]string{"9000": "python3", "53/udp": "systemd-resolve"}
+	result := planPorts(portTestPlan(t, "portainer", "pihole"), listeners, nil, nil)
+
+	byPort := map[int]PortConflict{}
+	for _, conflict := range result.Conflicts {
+		byPort[conflict.Port] = conflict
+	}
+	if got := result.Config.Overrides["portainer"].Ports["9000"]; got != 9001 || !byPort[9000].Resolved {
+		t.Errorf("portainer port 9000 moved to %d (conflict %+v), want 9001", got, byPort[9000])
+	}
+	if conflict := byPort[53]; conflict.Resolved || !reflect.DeepEqual(conflict.Holders, []string{"Pi-hole", "systemd-resolve"}) {
+		t.Errorf("port 53 conflict = %+v, want an unresolved conflict with systemd-resolve", conflict)
+	}
+}
+
+func TestPlanPortsIgnoresContainersBeingRedeployed(t *testing.T) {
+	listeners := map[string]string{"9000": "docker-proxy", "80": "pihole-FTL"}
+	published := map[string]string{"9000": "portainer"}
+	running := map[string]bool{"portainer": true, "pihole": true}
+
+	result := planPorts(portTestPlan(t, "portainer", "pihole"), listeners, published, running)
+	if len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %+v, want none when redeploying the same containers", result.Conflicts)
+	}
+}
+
+func TestPlanPortsOtherContainer(t *testing.T) {
+	published := map[string]string{"3000": "legacy-grafana"}
+	result := planPorts(portTestPlan(t, "grafana"), nil, published, nil)
+
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Holders[1] != "container legacy-grafana" {
+		t.Fatalf("conflicts = %+v, want grafana to collide with legacy-grafana", result.Conflicts)
+	}
+	if got := result.Config.Overrides["grafana"].Ports["3000"]; got != 3001 {
+		t.Errorf("grafana port 3000 moved to %d, want 3001", got)
+	}
+}
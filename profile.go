@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Deployment phases that task timings are grouped into
+const (
+	phaseSetup     = "setup"
+	phaseUpdate    = "update"
+	phaseDocker    = "docker"
+	phaseStorage   = "storage"
+	phasePortainer = "portainer"
+	phaseNetwork   = "network"
+	phaseIoT       = "iot"
+	phaseMedia     = "media"
+	phaseCustom    = "custom"
+)
+
+// slowestTaskCount is how many tasks a profile ranks
+const slowestTaskCount = 10
+
+// profileBaselineRuns is how many earlier runs of a playbook a profile is compared against
+const profileBaselineRuns = 5
+
+// A task is flagged as a regression when it is this much slower than its baseline
+const (
+	regressionRatio   = 1.5
+	regressionMinimum = 10 * time.Second
+)
+
+var (
+	// playPhases maps the plays of the individual playbooks onto phases
+	playPhases = map[string]string{
+		"Install Docker on Raspberry Pi":       phaseDocker,
+		"Configure Storage on Raspberry Pi":    phaseStorage,
+		"Deploy Portainer on Raspberry Pi":     phasePortainer,
+		"Deploy Network Stack on Raspberry Pi": phaseNetwork,
+		"Deploy IoT Stack on Raspberry Pi":     phaseIoT,
+		"Deploy Media Stack on Raspberry Pi":   phaseMedia,
+		"Deploy Custom Stack on Raspberry Pi":  phaseCustom,
+	}
+
+	stackTaskPattern   = regexp.MustCompile(`(?i)\b(network|iot|media|custom) stack\b`)
+	storageTaskPattern = regexp.MustCompile(`(?i)usb|nfs|cifs|smb|mount|partition|fstab|wipe|format|storage|log2ram|blkid|uuid`)
+	dockerTaskPattern  = regexp.MustCompile(`(?i)docker|gpg key|debian version|required system packages`)
+	updateTaskPattern  = regexp.MustCompile(`(?i)update|upgrade|reboot|package cache`)
+	nonAlphanumeric    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// TaskTiming is the wall-clock duration of one task in a run
+type TaskTiming struct {
+	Play       string    `json:"play"`
+	Task       string    `json:"task"`
+	Phase      string    `json:"phase"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// PhaseTiming is the total time spent in one phase
+type PhaseTiming struct {
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"durationMs"`
+	Tasks      int    `json:"tasks"`
+}
+
+// TaskComparison compares a task's duration with earlier runs of the same playbook
+type TaskComparison struct {
+	Task       string  `json:"task"`
+	Phase      string  `json:"phase"`
+	DurationMs int64   `json:"durationMs"`
+	BaselineMs int64   `json:"baselineMs"`
+	DeltaMs    int64   `json:"deltaMs"`
+	Ratio      float64 `json:"ratio"`
+	Regression bool    `json:"regression"`
+}
+
+// PhaseComparison compares a phase's duration with earlier runs of the same playbook
+type PhaseComparison struct {
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"durationMs"`
+	BaselineMs int64  `json:"baselineMs"`
+	DeltaMs    int64  `json:"deltaMs"`
+}
+
+// ProfileComparison sets a run against the median of earlier runs of the same playbook
+type ProfileComparison struct {
+	BaselineRunIDs []string          `json:"baselineRunIds"`
+	TotalMs        int64             `json:"totalMs"`
+	BaselineMs     int64             `json:"baselineMs"`
+	Phases         []PhaseComparison `json:"phases"`
+	Tasks          []TaskComparison  `json:"tasks"`
+	Regressions    []TaskComparison  `json:"regressions"`
+}
+
+// RunProfile ranks where the time in a run went
+type RunProfile struct {
+	RunID        string             `json:"runId"`
+	Playbook     string             `json:"playbook"`
+	Host         string             `json:"host"`
+	StartedAt    time.Time          `json:"startedAt"`
+	TotalMs      int64              `json:"totalMs"`
+	Tasks        []TaskTiming       `json:"tasks"`
+	SlowestTasks []TaskTiming       `json:"slowestTasks"`
+	Phases       []PhaseTiming      `json:"phases"`
+	Comparison   *ProfileComparison `json:"comparison,omitempty"`
+}
+
+// classifyTask assigns a task to a deployment phase. Plays of the individual
+// playbooks name their phase; inside main.yml, where everything is one play,
+// the task name decides.
+func classifyTask(play, task string) string {
+	if phase, ok := playPhases[play]; ok {
+		return phase
+	}
+
+	normalised := nonAlphanumeric.ReplaceAllString(strings.ToLower(task), "")
+	for _, spec := range currentCatalog().containerSpecs() {
+		name := nonAlphanumeric.ReplaceAllString(spec.Name, "")
+		short := strings.SplitN(spec.Name, "-", 2)[0]
+		if strings.Contains(normalised, name) || strings.Contains(normalised, short) {
+			return stackPhase(spec.Stack)
+		}
+	}
+
+	switch {
+	case stackTaskPattern.MatchString(task):
+		return stackPhase(strings.ToLower(stackTaskPattern.FindStringSubmatch(task)[1]))
+	case storageTaskPattern.MatchString(task):
+		return phaseStorage
+	case dockerTaskPattern.MatchString(task):
+		return phaseDocker
+	case updateTaskPattern.MatchString(task):
+		return phaseUpdate
+	}
+	return phaseSetup
+}
+
+// stackPhase maps a catalog stack name onto its phase
+func stackPhase(stack string) string {
+	switch stack {
+	case "portainer":
+		return phasePortainer
+	case "network":
+		return phaseNetwork
+	case "iot":
+		return phaseIoT
+	case "media":
+		return phaseMedia
+	case customStack:
+		return phaseCustom
+	}
+	return phaseSetup
+}
+
+// profileDir returns where task timings are stored, one file per run
+func (h *runHistory) profileDir() string {
+	return filepath.Join(filepath.Dir(h.path), "profiles")
+}
+
+// profilePath returns the timings file for a run
+func (h *runHistory) profilePath(id string) string {
+	return filepath.Join(h.profileDir(), id+".json")
+}
+
+// saveTimings stores the task timings of a run
+func (h *runHistory) saveTimings(id string, timings []TaskTiming) error {
+	for i := range timings {
+		timings[i].Phase = classifyTask(timings[i].Play, timings[i].Task)
+	}
+
+	data, err := json.Marshal(timings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task timings: %v", err)
+	}
+	if err := os.MkdirAll(h.profileDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create profile directory: %v", err)
+	}
+	return os.WriteFile(h.profilePath(id), data, 0600)
+}
+
+// loadTimings reads the task timings of a run
+func (h *runHistory) loadTimings(id string) ([]TaskTiming, error) {
+	data, err := os.ReadFile(h.profilePath(id))
+	if err != nil {
+		return nil, fmt.Errorf("no timing profile recorded for run %s", id)
+	}
+
+	var timings []TaskTiming
+	if err := json.Unmarshal(data, &timings); err != nil {
+		return nil, fmt.Errorf("failed to parse timing profile: %v", err)
+	}
+	return timings, nil
+}
+
+// newRunProfile ranks the tasks and phases of a run
+func newRunProfile(record RunRecord, timings []TaskTiming) RunProfile {
+	profile := RunProfile{
+		RunID:     record.ID,
+		Playbook:  record.Playbook,
+		Host:      record.Host,
+		StartedAt: record.StartedAt,
+		Tasks:     timings,
+		Phases:    phaseTimings(timings),
+	}
+	for _, timing := range timings {
+		profile.TotalMs += timing.DurationMs
+	}
+
+	profile.SlowestTasks = append([]TaskTiming(nil), timings...)
+	sort.SliceStable(profile.SlowestTasks, func(i, j int) bool {
+		return profile.SlowestTasks[i].DurationMs > profile.SlowestTasks[j].DurationMs
+	})
+	if len(profile.SlowestTasks) > slowestTaskCount {
+		profile.SlowestTasks = profile.SlowestTasks[:slowestTaskCount]
+	}
+	return profile
+}
+
+// phaseTimings totals task durations per phase, slowest first
+func phaseTimings(timings []TaskTiming) []PhaseTiming {
+	index := map[string]int{}
+	phases := []PhaseTiming{}
+	for _, timing := range timings {
+		i, ok := index[timing.Phase]
+		if !ok {
+			i = len(phases)
+			index[timing.Phase] = i
+			phases = append(phases, PhaseTiming{Phase: timing.Phase})
+		}
+		phases[i].DurationMs += timing.DurationMs
+		phases[i].Tasks++
+	}
+
+	sort.SliceStable(phases, func(i, j int) bool { return phases[i].DurationMs > phases[j].DurationMs })
+	return phases
+}
+
+// compareProfile sets profile against the median of its baseline runs
+func compareProfile(profile RunProfile, baselines map[string][]TaskTiming, baselineIDs []string) *ProfileComparison {
+	comparison := &ProfileComparison{
+		BaselineRunIDs: baselineIDs,
+		TotalMs:        profile.TotalMs,
+		Phases:         []PhaseComparison{},
+		Tasks:          []TaskComparison{},
+		Regressions:    []TaskComparison{},
+	}
+
+	// Collect each task's and phase's durations across the baseline runs
+	taskSamples := map[string][]int64{}
+	phaseSamples := map[string][]int64{}
+	var totals []int64
+	for _, id := range baselineIDs {
+		var total int64
+		phaseTotals := map[string]int64{}
+		for _, timing := range baselines[id] {
+			taskSamples[timing.Task] = append(taskSamples[timing.Task], timing.DurationMs)
+			phaseTotals[timing.Phase] += timing.DurationMs
+			total += timing.DurationMs
+		}
+		for phase, ms := range phaseTotals {
+			phaseSamples[phase] = append(phaseSamples[phase], ms)
+		}
+		totals = append(totals, total)
+	}
+	comparison.BaselineMs = median(totals)
+
+	for _, phase := range profile.Phases {
+		baseline := median(phaseSamples[phase.Phase])
+		comparison.Phases = append(comparison.Phases, PhaseComparison{
+			Phase:      phase.Phase,
+			DurationMs: phase.DurationMs,
+			BaselineMs: baseline,
+			DeltaMs:    phase.DurationMs - baseline,
+		})
+	}
+
+	for _, timing := range profile.Tasks {
+		samples, ok := taskSamples[timing.Task]
+		if !ok {
+			continue
+		}
+		baseline := median(samples)
+		task := TaskComparison{
+			Task:       timing.Task,
+			Phase:      timing.Phase,
+			DurationMs: timing.DurationMs,
+			BaselineMs: baseline,
+			DeltaMs:    timing.DurationMs - baseline,
+		}
+		if baseline > 0 {
+			task.Ratio = float64(timing.DurationMs) / float64(baseline)
+		}
+		task.Regression = task.Ratio >= regressionRatio && time.Duration(task.DeltaMs)*time.Millisecond >= regressionMinimum
+		comparison.Tasks = append(comparison.Tasks, task)
+		if task.Regression {
+			comparison.Regressions = append(comparison.Regressions, task)
+		}
+	}
+
+	sort.SliceStable(comparison.Tasks, func(i, j int) bool { return comparison.Tasks[i].DeltaMs > comparison.Tasks[j].DeltaMs })
+	sort.SliceStable(comparison.Regressions, func(i, j int) bool {
+		return comparison.Regressions[i].DeltaMs > comparison.Regressions[j].DeltaMs
+	})
+	return comparison
+}
+
+// median returns the median of values, or 0 if there are none
+func median(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// GetRunProfile ranks the slowest tasks and phases of a run and compares them with
+// earlier runs of the same playbook on the same host
+func (a *App) GetRunProfile(id string) (RunProfile, error) {
+	a.history.mu.Lock()
+	records, err := a.history.load()
+	a.history.mu.Unlock()
+	if err != nil {
+		return RunProfile{}, err
+	}
+
+	var record *RunRecord
+	for i := range records {
+		if records[i].ID == id {
+			record = &records[i]
+			break
+		}
+	}
+	if record == nil {
+		return RunProfile{}, fmt.Errorf("run %s not found in history", id)
+	}
+
+	timings, err := a.history.loadTimings(id)
+	if err != nil {
+		return RunProfile{}, err
+	}
+	profile := newRunProfile(*record, timings)
+
+	// Records are newest first, so earlier runs follow this one
+	baselines := map[string][]TaskTiming{}
+	var baselineIDs []string
+	for _, earlier := range records {
+		if len(baselineIDs) == profileBaselineRuns {
+			break
+		}
+		if !earlier.StartedAt.Before(record.StartedAt) || earlier.Playbook != record.Playbook || earlier.Host != record.Host {
+			continue
+		}
+		if earlier.Options.StartAtTask != "" || len(earlier.Options.Tags) > 0 {
+			// Partial runs would skew the baseline
+			continue
+		}
+		if timings, err := a.history.loadTimings(earlier.ID); err == nil {
+			baselines[earlier.ID] = timings
+			baselineIDs = append(baselineIDs, earlier.ID)
+		}
+	}
+	if len(baselineIDs) > 0 {
+		profile.Comparison = compareProfile(profile, baselines, baselineIDs)
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyTask(t *testing.T) {
+	tests := []struct {
+		name  string
+		play  string
+		task  string
+		phase string
+	}{
+		{"play names the phase", "Deploy Media Stack on Raspberry Pi", "Create directories", phaseMedia},
+		{"container name", "Setup Raspberry Pi", "Start Pi-hole container", phaseNetwork},
+		{"hyphenated container name", "Setup Raspberry Pi", "Pull nginx proxy manager image", phaseNetwork},
+		{"stack in task name", "Setup Raspberry Pi", "Create IoT stack directories", phaseIoT},
+		{"custom stack", "Setup Raspberry Pi", "Write custom stack compose file", phaseCustom},
+		{"storage", "Setup Raspberry Pi", "Mount USB drive", phaseStorage},
+		{"docker", "Setup Raspberry Pi", "Add Docker GPG key", phaseDocker},
+		{"update", "Setup Raspberry Pi", "Update apt package cache", phaseUpdate},
+		{"fallback", "Setup Raspberry Pi", "Set hostname", phaseSetup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyTask(tt.play, tt.task); got != tt.phase {
+				t.Errorf("classifyTask(%q, %q) = %q, want %q", tt.play, tt.task, got, tt.phase)
+			}
+		})
+	}
+}
+
+func TestCompareProfile(t *testing.T) {
+	baselines := map[string][]TaskTiming{
+		"a": {
+			{Task: "Pull images", Phase: phaseMedia, DurationMs: 20000},
+			{Task: "Set hostname", Phase: phaseSetup, DurationMs: 1000},
+		},
+		"b": {
+			{Task: "Pull images", Phase: phaseMedia, DurationMs: 30000},
+			{Task: "Set hostname", Phase: phaseSetup, DurationMs: 1000},
+		},
+		"c": {
+			{Task: "Pull images", Phase: phaseMedia, DurationMs: 40000},
+			{Task: "Set hostname", Phase: phaseSetup, DurationMs: 3000},
+		},
+	}
+	timings := []TaskTiming{
+		{Task: "Pull images", Phase: phaseMedia, DurationMs: 60000},
+		{Task: "Set hostname", Phase: phaseSetup, DurationMs: 2000},
+		{Task: "New task", Phase: phaseSetup, DurationMs: 5000},
+	}
+	profile := newRunProfile(RunRecord{ID: "d"}, timings)
+
+	comparison := compareProfile(profile, baselines, []string{"a", "b", "c"})
+	if comparison.TotalMs != 67000 || comparison.BaselineMs != 31000 {
+		t.Errorf("totals = %d against %d, want 67000 against 31000", comparison.TotalMs, comparison.BaselineMs)
+	}
+
+	wantPhases := []PhaseComparison{
+		{Phase: phaseMedia, DurationMs: 60000, BaselineMs: 30000, DeltaMs: 30000},
+		{Phase: phaseSetup, DurationMs: 7000, BaselineMs: 1000, DeltaMs: 6000},
+	}
+	if !reflect.DeepEqual(comparison.Phases, wantPhases) {
+		t.Errorf("phases = %+v, want %+v", comparison.Phases, wantPhases)
+	}
+
+	// Tasks missing from every baseline are not compared; a task is only a
+	// regression when it is both proportionally and absolutely slower
+	wantTasks := []TaskComparison{
+		{Task: "Pull images", Phase: phaseMedia, DurationMs: 60000, BaselineMs: 30000, DeltaMs: 30000, Ratio: 2, Regression: true},
+		{Task: "Set hostname", Phase: phaseSetup, DurationMs: 2000, BaselineMs: 1000, DeltaMs: 1000, Ratio: 2},
+	}
+	if !reflect.DeepEqual(comparison.Tasks, wantTasks) {
+		t.Errorf("tasks = %+v, want %+v", comparison.Tasks, wantTasks)
+	}
+	if !reflect.DeepEqual(comparison.Regressions, wantTasks[:1]) {
+		t.Errorf("regressions = %+v, want %+v", comparison.Regressions, wantTasks[:1])
+	}
+}
+
+func TestCompareProfileWithoutBaselines(t *testing.T) {
+	profile := newRunProfile(RunRecord{ID: "a"}, []TaskTiming{{Task: "Set hostname", Phase: phaseSetup, DurationMs: 1000}})
+
+	comparison := compareProfile(profile, nil, nil)
+	if comparison.BaselineMs != 0 || len(comparison.Tasks) != 0 || len(comparison.Regressions) != 0 {
+		t.Errorf("compareProfile() without baselines = %+v", comparison)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []int64
+		want   int64
+	}{
+		{nil, 0},
+		{[]int64{5}, 5},
+		{[]int64{9, 1, 5}, 5},
+		{[]int64{4, 1, 3, 2}, 2},
+	}
+
+	for _, tt := range tests {
+		if got := median(tt.values); got != tt.want {
+			t.Errorf("median(%v) = %d, want %d", tt.values, got, tt.want)
+		}
+	}
+}
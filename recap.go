@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+var (
+	recapLinePattern    = regexp.MustCompile(`^(\S+)\s*:\s*((?:[a-z]+=\d+\s*)+)$`)
+	recapCounterPattern = regexp.MustCompile(`([a-z]+)=(\d+)`)
+)
+
+// HostSummary holds the PLAY RECAP counters for one host
+type HostSummary struct {
+	Host        string `json:"host"`
+	Ok          int    `json:"ok"`
+	Changed     int    `json:"changed"`
+	Unreachable int    `json:"unreachable"`
+	Failed      int    `json:"failed"`
+	Skipped     int    `json:"skipped"`
+	Rescued     int    `json:"rescued"`
+	Ignored     int    `json:"ignored"`
+}
+
+// RunSummary is the parsed PLAY RECAP of a run
+type RunSummary struct {
+	Hosts []HostSummary `json:"hosts"`
+	// Recap reports whether a PLAY RECAP was seen at all
+	Recap bool `json:"recap"`
+	// Success is true when every host finished without failures or unreachable errors
+	Success bool `json:"success"`
+}
+
+// parseRecapLine parses a "host : ok=1 changed=0 ..." line from a PLAY RECAP
+func parseRecapLine(line string) (HostSummary, bool) {
+	match := recapLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return HostSummary{}, false
+	}
+
+	host := HostSummary{Host: match[1]}
+	for _, counter := range recapCounterPattern.FindAllStringSubmatch(match[2], -1) {
+		value, _ := strconv.Atoi(counter[2])
+		switch counter[1] {
+		case "ok":
+			host.Ok = value
+		case "changed":
+			host.Changed = value
+		case "unreachable":
+			host.Unreachable = value
+		case "failed":
+			host.Failed = value
+		case "skipped":
+			host.Skipped = value
+		case "rescued":
+			host.Rescued = value
+		case "ignored":
+			host.Ignored = value
+		}
+	}
+	return host, true
+}
+
+// newRunSummary builds a summary from recap entries in the order hosts were reported
+func newRunSummary(hosts []HostSummary, recap bool) RunSummary {
+	summary := RunSummary{Hosts: hosts, Recap: recap, Success: recap && len(hosts) > 0}
+	if summary.Hosts == nil {
+		summary.Hosts = []HostSummary{}
+	}
+	for _, host := range hosts {
+		if host.Failed > 0 || host.Unreachable > 0 {
+			summary.Success = false
+		}
+	}
+	return summary
+}
+
+// problemHosts lists hosts that failed or were unreachable
+func (s RunSummary) problemHosts() []string {
+	var hosts []string
+	for _, host := range s.Hosts {
+		if host.Failed > 0 || host.Unreachable > 0 {
+			hosts = append(hosts, host.Host)
+		}
+	}
+	return hosts
+}
+
+// finishRun emits the completion event for a run. When a recap was seen, success is
+// decided from its counters rather than only from the exit status.
+func (ar *AnsibleRunner) finishRun(ctx context.Context, runErr error) error {
+	summary := newRunSummary(nil, false)
+	if tracker := runTrackerFrom(ctx); tracker != nil {
+		summary = tracker.summary()
+	}
+
+	// All buffered output must reach the frontend before the run is reported as over
+	outputStreamFrom(ctx).close()
+
+	if runErr == nil && summary.Recap && !summary.Success {
+		runErr = fmt.Errorf("run reported failures on: %s", strings.Join(summary.problemHosts(), ", "))
+	}
+
+	if runErr != nil {
+		runErr = redactorFrom(ctx).redactError(runErr)
+		runtime.EventsEmit(ctx, "ansibleError", runErr.Error())
+		return runErr
+	}
+
+	runtime.EventsEmit(ctx, "ansibleComplete", map[string]interface{}{
+		"message": "Playbook completed successfully",
+		"summary": summary,
+	})
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRecapLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want HostSummary
+		ok   bool
+	}{
+		{
+			name: "all counters",
+			line: "pi                         : ok=12   changed=3    unreachable=0    failed=1    skipped=4    rescued=0    ignored=2",
+			want: HostSummary{Host: "pi", Ok: 12, Changed: 3, Failed: 1, Skipped: 4, Ignored: 2},
+			ok:   true,
+		},
+		{
+			name: "unreachable",
+			line: "  192.168.1.20 : ok=0 changed=0 unreachable=1 failed=0",
+			want: HostSummary{Host: "192.168.1.20", Unreachable: 1},
+			ok:   true,
+		},
+		{
+			name: "unknown counter ignored",
+			line: "pi : ok=1 future=7",
+			want: HostSummary{Host: "pi", Ok: 1},
+			ok:   true,
+		},
+		{name: "task line", line: "TASK [Install Docker] ****", ok: false},
+		{name: "recap header", line: "PLAY RECAP *********************************************************************", ok: false},
+		{name: "message with colon", line: "fatal: [pi]: FAILED! => {\"msg\": \"ok=1\"}", ok: false},
+		{name: "empty", line: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRecapLine(tt.line)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRecapLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewRunSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []HostSummary
+		recap   bool
+		success bool
+		problem []string
+	}{
+		{"no recap", nil, false, false, nil},
+		{"recap without hosts", nil, true, false, nil},
+		{"all ok", []HostSummary{{Host: "pi", Ok: 3}}, true, true, nil},
+		{"failed host", []HostSummary{{Host: "a", Ok: 1}, {Host: "b", Failed: 1}}, true, false, []string{"b"}},
+		{"unreachable host", []HostSummary{{Host: "a", Unreachable: 1}}, true, false, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := newRunSummary(tt.hosts, tt.recap)
+			if summary.Success != tt.success {
+				t.Errorf("Success = %v, want %v", summary.Success, tt.success)
+			}
+			if summary.Hosts == nil {
+				t.Error("Hosts is nil, want an empty list")
+			}
+			if got := summary.problemHosts(); !reflect.DeepEqual(got, tt.problem) {
+				t.Errorf("problemHosts() = %v, want %v", got, tt.problem)
+			}
+		})
+	}
+}
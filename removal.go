@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// removePlaybook removes containers and, optionally, their directories
+const removePlaybook = "remove-components.yml"
+
+// RemovalItem is a container or directory that a removal deletes
+type RemovalItem struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+	// Size is the disk usage of a directory, as reported by du
+	Size string `json:"size,omitempty"`
+}
+
+// RemovalPlan lists exactly what removing components deletes from a host
+type RemovalPlan struct {
+	Components    []string      `json:"components"`
+	Containers    []RemovalItem `json:"containers"`
+	Directories   []RemovalItem `json:"directories"`
+	RemoveNetwork bool          `json:"removeNetwork"`
+	KeepData      bool          `json:"keepData"`
+	Warnings      []string      `json:"warnings"`
+}
+
+// removal is what a RemoveComponents or RemoveStack call deletes
+type removal struct {
+	components []CatalogComponent
+	containers []string
+	dirs       []string
+}
+
+// componentPaths returns the host paths a component's containers own, as
+// placeholders. Shared media folders are never included.
+func (c CatalogComponent) componentPaths() []string {
+	var paths []string
+	for _, spec := range c.containers {
+		paths = append(paths, spec.Dirs...)
+		for _, file := range spec.Files {
+			paths = append(paths, file.Path)
+		}
+		if spec.Credentials != nil {
+			paths = append(paths, spec.Credentials.Path)
+		}
+		for _, volume := range spec.Volumes {
+			paths = append(paths, strings.SplitN(volume, ":", 2)[0])
+		}
+	}
+
+	owned := []string{}
+	for _, p := range paths {
+		if strings.HasPrefix(p, "{data}/") || strings.HasPrefix(p, "{config}/") || strings.HasPrefix(p, "{volume}/") {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// removablePath reports whether dir is safe to delete: strictly inside the volume
+// path and outside the shared media, compose and stack directories
+func removablePath(volumePath, dir string) bool {
+	if path.Clean(dir) != dir || !strings.HasPrefix(dir, volumePath+"/") {
+		return false
+	}
+	for _, shared := range []string{"data", "config", "media", "compose"} {
+		root := volumePath + "/" + shared
+		if dir == root || (shared == "media" && strings.HasPrefix(dir, root+"/")) {
+			return false
+		}
+	}
+	return true
+}
+
+// topmostPaths drops every path that lies inside another path in the list
+func topmostPaths(paths []string) []string {
+	sorted := append([]string{}, paths...)
+	sort.Strings(sorted)
+	var result []string
+	for _, p := range sorted {
+		// A sibling such as /a/b-x sorts between /a/b and /a/b/c, so check every kept path
+		inside := false
+		for _, kept := range result {
+			inside = inside || p == kept || strings.HasPrefix(p, kept+"/")
+		}
+		if !inside {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// newRemoval works out the containers and directories to delete for the given
+// components, dependents first. Removing a whole stack without keeping data also
+// deletes its compose project.
+func newRemoval(volumePath string, ids []string, stack string, keepData bool) removal {
+	run := &nativeRun{vars: map[string]interface{}{"volume_path": volumePath}}
+
+	var r removal
+	var dirs []string
+	for i := len(currentCatalog().Components) - 1; i >= 0; i-- {
+		component := currentCatalog().Components[i]
+		if !containsString(ids, component.ID) {
+			continue
+		}
+		r.components = append(r.components, component)
+		for j := len(component.containers) - 1; j >= 0; j-- {
+			r.containers = append(r.containers, component.containers[j].Name)
+		}
+		for _, p := range run.expandAll(component.componentPaths()) {
+			if removablePath(volumePath, p) {
+				dirs = append(dirs, p)
+			}
+		}
+	}
+
+	if keepData {
+		return r
+	}
+	if stack != "" {
+		dirs = append(dirs, path.Join(run.expand(composeRoot), stack))
+	}
+	r.dirs = topmostPaths(dirs)
+	return r
+}
+
+// extraVars returns the variables remove-components.yml expects
+func (r removal) extraVars(volumePath string) map[string]interface{} {
+	return map[string]interface{}{
+		"volume_path":       volumePath,
+		"remove_containers": r.containers,
+		"remove_dirs":       append([]string{}, r.dirs...),
+	}
+}
+
+// stackComponents returns the ids of every component in a stack
+func stackComponents(stack string) []string {
+	var ids []string
+	for _, component := range currentCatalog().Components {
+		if component.Stack == stack {
+			ids = append(ids, component.ID)
+		}
+	}
+	return ids
+}
+
+// validateRemovalInputs checks the inputs of a component removal
+func validateRemovalInputs(host, user, volumePath string, components []string) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateVolumePath(v, "volumePath", volumePath)
+	if len(components) == 0 {
+		v.add("components", "select at least one component to remove")
+	}
+	for _, id := range components {
+		if _, ok := currentCatalog().component(id); !ok {
+			v.add("components."+id, "unknown component")
+		}
+	}
+	return v.err()
+}
+
+// validateStackRemovalInputs checks the inputs of a stack removal
+func validateStackRemovalInputs(host, user, volumePath, stack string) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateVolumePath(v, "volumePath", volumePath)
+	if _, ok := currentCatalog().stack(stack); !ok {
+		v.add("stack", "unknown stack")
+	}
+	return v.err()
+}
+
+// removalProbeScript lists the host's containers, the containers on the shared
+// network ("!" if it does not exist) and the size of each existing directory
+func removalProbeScript(dirs []string) string {
+	quoted := make([]string, len(dirs))
+	for i, dir := range dirs {
+		quoted[i] = shellQuote(dir)
+	}
+	return strings.Join([]string{
+		"docker ps -a --format '{{.Names}}' 2>/dev/null",
+		"echo '--'",
+		"docker network inspect docker_network --format '{{range .Containers}}{{.Name}} {{end}}' 2>/dev/null || echo '!'",
+		"echo '--'",
+		fmt.Sprintf("for d in %s; do [ -e \"$d\" ] && du -sh \"$d\" 2>/dev/null; done; true", strings.Join(quoted, " ")),
+	}, "\n")
+}
+
+// parseRemovalProbe splits the output of removalProbeScript into the existing
+// containers, the containers on the network (nil if it is missing) and directory sizes
+func parseRemovalProbe(output string) (containers, attached []string, networkExists bool, sizes map[string]string) {
+	sections := [3][]string{}
+	section := 0
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "--":
+			if section < 2 {
+				section++
+			}
+		case line != "":
+			sections[section] = append(sections[section], line)
+		}
+	}
+
+	containers = sections[0]
+	networkExists = !(len(sections[1]) == 1 && sections[1][0] == "!")
+	if networkExists {
+		for _, line := range sections[1] {
+			attached = append(attached, strings.Fields(line)...)
+		}
+	}
+	sizes = map[string]string{}
+	for _, line := range sections[2] {
+		if fields := strings.SplitN(line, "\t", 2); len(fields) == 2 {
+			sizes[fields[1]] = fields[0]
+		}
+	}
+	return containers, attached, networkExists, sizes
+}
+
+// previewRemoval probes the host and describes what the removal would delete
+func (a *App) previewRemoval(host, user, password string, r removal, keepData bool) (RemovalPlan, error) {
+	output, err := hostSudo(host, user, password, a.operations.get(host).connectTimeout(), removalProbeScript(r.dirs))
+	if err != nil {
+		return RemovalPlan{}, fmt.Errorf("failed to inspect host: %v", err)
+	}
+	existing, attached, networkExists, sizes := parseRemovalProbe(output)
+
+	plan := RemovalPlan{
+		Components:  []string{},
+		Containers:  []RemovalItem{},
+		Directories: []RemovalItem{},
+		KeepData:    keepData,
+		Warnings:    []string{},
+	}
+	removed := map[string]bool{}
+	for _, component := range r.components {
+		plan.Components = append(plan.Components, component.Name)
+		removed[component.ID] = true
+	}
+	for _, name := range r.containers {
+		plan.Containers = append(plan.Containers, RemovalItem{Name: name, Exists: containsString(existing, name)})
+	}
+	for _, dir := range r.dirs {
+		size, ok := sizes[dir]
+		plan.Directories = append(plan.Directories, RemovalItem{Name: dir, Exists: ok, Size: size})
+	}
+
+	if networkExists {
+		plan.RemoveNetwork = true
+		for _, name := range attached {
+			if !containsString(r.containers, name) {
+				plan.RemoveNetwork = false
+				break
+			}
+		}
+	}
+
+	for _, component := range currentCatalog().Components {
+		if removed[component.ID] || !containsString(existing, component.mainContainer().Name) {
+			continue
+		}
+		for _, dependency := range component.Dependencies {
+			if removed[dependency] {
+				needed, _ := currentCatalog().component(dependency)
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s is still deployed and depends on %s", component.Name, needed.Name))
+			}
+		}
+	}
+	return plan, nil
+}
+
+// PreviewRemoveComponents lists the containers, directories and network that
+// RemoveComponents would delete, without changing anything
+func (a *App) PreviewRemoveComponents(host, user, password, volumePath string, components []string, keepData bool) (RemovalPlan, error) {
+	if err := validateRemovalInputs(host, user, volumePath, components); err != nil {
+		return RemovalPlan{}, err
+	}
+	return a.previewRemoval(host, user, password, newRemoval(volumePath, components, "", keepData), keepData)
+}
+
+// PreviewRemoveStack lists what RemoveStack would delete, without changing anything
+func (a *App) PreviewRemoveStack(host, user, password, volumePath, stack string, keepData bool) (RemovalPlan, error) {
+	if err := validateStackRemovalInputs(host, user, volumePath, stack); err != nil {
+		return RemovalPlan{}, err
+	}
+	return a.previewRemoval(host, user, password, newRemoval(volumePath, stackComponents(stack), stack, keepData), keepData)
+}
+
+// RemoveComponents stops and removes the components' containers and the shared
+// network once nothing uses it. Unless keepData is set, the components'
+// directories under the data and config folders are deleted too. The removal runs
+// on the named backend, or the default one if backend is empty.
+func (a *App) RemoveComponents(host, user, password, volumePath string, components []string, keepData bool, backend string) (RunSummary, error) {
+	if err := validateRemovalInputs(host, user, volumePath, components); err != nil {
+		return RunSummary{}, err
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", "Removing components...")
+
+	r := newRemoval(volumePath, components, "", keepData)
+	return a.runAnsiblePlaybook(removePlaybook, backend, host, user, password, r.extraVars(volumePath))
+}
+
+// RemoveStack removes every component of a stack, like RemoveComponents. Unless
+// keepData is set, the stack's compose project is deleted as well.
+func (a *App) RemoveStack(host, user, password, volumePath, stack string, keepData bool, backend string) (RunSummary, error) {
+	if err := validateStackRemovalInputs(host, user, volumePath, stack); err != nil {
+		return RunSummary{}, err
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", "Removing stack...")
+
+	r := newRemoval(volumePath, stackComponents(stack), stack, keepData)
+	return a.runAnsiblePlaybook(removePlaybook, backend, host, user, password, r.extraVars(volumePath))
+}
+
+// removeComponents mirrors remove-components.yml
+func (r *nativeRun) removeComponents() error {
+	containers, _ := r.listVar("remove_containers")
+	dirs, _ := r.listVar("remove_dirs")
+
+	if err := r.task("Remove containers", func() (bool, error) {
+		changed := false
+		for _, name := range containers {
+			out, err := r.sudo(fmt.Sprintf("if docker inspect %[1]s >/dev/null 2>&1; then docker rm -f %[1]s >/dev/null && echo removed; fi", shellQuote(name)))
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || strings.Contains(out, "removed")
+		}
+		return changed, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := r.task("Remove component directories", func() (bool, error) {
+		changed := false
+		for _, dir := range dirs {
+			out, err := r.sudo(fmt.Sprintf("if [ -e %[1]s ]; then rm -rf %[1]s && echo removed; fi", shellQuote(dir)))
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || strings.Contains(out, "removed")
+		}
+		return changed, nil
+	}); err != nil {
+		return err
+	}
+
+	return r.task("Remove unused Docker network", func() (bool, error) {
+		out, err := r.sudo("docker network inspect docker_network --format '{{len .Containers}}' 2>/dev/null || true")
+		if err != nil || strings.TrimSpace(out) != "0" {
+			return false, err
+		}
+		_, err = r.sudo("docker network rm docker_network >/dev/null")
+		return err == nil, err
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemovablePath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{"/mnt/docker/data/sonarr", true},
+		{"/mnt/docker/config/grafana", true},
+		{"/mnt/docker/compose/media", true},
+		{"/mnt/docker", false},
+		{"/mnt/docker/data", false},
+		{"/mnt/docker/config", false},
+		{"/mnt/docker/compose", false},
+		{"/mnt/docker/media", false},
+		{"/mnt/docker/media/tv", false},
+		{"/mnt/docker/data/../../etc", false},
+		{"/mnt/docker/data/sonarr/", false},
+		{"/mnt/dockerx/data/sonarr", false},
+		{"/etc/sonarr", false},
+	}
+
+	for _, tt := range tests {
+		if got := removablePath("/mnt/docker", tt.dir); got != tt.want {
+			t.Errorf("removablePath(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestTopmostPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{"empty", nil, nil},
+		{"nested", []string{"/d/grafana/provisioning", "/d/grafana", "/d/grafana/provisioning/datasources"}, []string{"/d/grafana"}},
+		{"duplicates", []string{"/d/a", "/d/a"}, []string{"/d/a"}},
+		{"shared prefix is not nesting", []string{"/d/node", "/d/nodered"}, []string{"/d/node", "/d/nodered"}},
+		{"sibling sorts between", []string{"/d/b", "/d/b-x", "/d/b/c"}, []string{"/d/b", "/d/b-x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topmostPaths(tt.paths); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("topmostPaths(%v) = %v, want %v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRemoval(t *testing.T) {
+	activeCatalog.Store(nil)
+
+	kept := newRemoval("/mnt/docker", []string{"grafana", "influxdb"}, "", true)
+	if want := []string{"grafana", "influxdb"}; !reflect.DeepEqual(kept.containers, want) {
+		t.Errorf("containers = %v, want dependents first %v", kept.containers, want)
+	}
+	if len(kept.dirs) != 0 {
+		t.Errorf("dirs = %v, want none when keeping data", kept.dirs)
+	}
+
+	stack := newRemoval("/mnt/docker", stackComponents("media"), "media", false)
+	for _, dir := range stack.dirs {
+		if dir != "/mnt/docker/compose/media" && !removablePath("/mnt/docker", dir) {
+			t.Errorf("stack removal deletes %s", dir)
+		}
+	}
+	if !containsString(stack.dirs, "/mnt/docker/compose/media") {
+		t.Errorf("dirs = %v, want the stack's compose project", stack.dirs)
+	}
+}
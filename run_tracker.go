@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	taskLinePattern    = regexp.MustCompile(`^(?:TASK|RUNNING HANDLER) \[(.*)\]`)
+	playLinePattern    = regexp.MustCompile(`^PLAY \[(.*)\]`)
+	failureLinePattern = regexp.MustCompile(`^(?:fatal|failed): \[([^\]]+)\]`)
+)
+
+// runTrackerKey is the context key under which a run's tracker is stored
+type runTrackerKey struct{}
+
+// runTracker follows a run's output to record which task failed on which hosts,
+// how long each task took and the counters from its PLAY RECAP
+type runTracker struct {
+	mu          sync.Mutex
+	currentTask string
+	failedTask  string
+	failedHosts []string
+	pending     []string
+	inRecap     bool
+	sawRecap    bool
+	recap       []HostSummary
+	currentPlay string
+	timings     []TaskTiming
+	taskOpen    bool
+	// transient is why the first failure looks worth retrying, if it does
+	transient        string
+	pendingTransient string
+	upgrades         []UpgradeResult
+}
+
+// withRunTracker attaches a tracker to ctx so every backend's output reaches it
+func withRunTracker(ctx context.Context, tracker *runTracker) context.Context {
+	return context.WithValue(ctx, runTrackerKey{}, tracker)
+}
+
+// runTrackerFrom returns the tracker attached to ctx, if any
+func runTrackerFrom(ctx context.Context) *runTracker {
+	if ctx == nil {
+		return nil
+	}
+	tracker, _ := ctx.Value(runTrackerKey{}).(*runTracker)
+	return tracker
+}
+
+// observe updates the tracker from one line of Ansible-style output
+func (t *runTracker) observe(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inRecap {
+		if host, ok := parseRecapLine(line); ok {
+			t.addRecap(host)
+			return
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "...ignoring"):
+		// The failures just reported were covered by ignore_errors
+		t.pending = nil
+		t.pendingTransient = ""
+	case failureLinePattern.MatchString(line):
+		host := failureLinePattern.FindStringSubmatch(line)[1]
+		// Delegated tasks report as "host -> delegate"
+		host = strings.TrimSpace(strings.SplitN(host, "->", 2)[0])
+		t.pending = append(t.pending, host)
+		if t.pendingTransient == "" {
+			t.pendingTransient = transientReason(line)
+		}
+	case strings.HasPrefix(line, "TASK [") || strings.HasPrefix(line, "PLAY ") || strings.HasPrefix(line, "RUNNING HANDLER ["):
+		t.commit()
+		now := time.Now()
+		t.closeTask(now)
+		t.inRecap = strings.HasPrefix(line, "PLAY RECAP")
+		t.sawRecap = t.sawRecap || t.inRecap
+		if match := playLinePattern.FindStringSubmatch(line); match != nil {
+			t.currentPlay = match[1]
+		}
+		if match := taskLinePattern.FindStringSubmatch(line); match != nil {
+			t.currentTask = match[1]
+			t.timings = append(t.timings, TaskTiming{Play: t.currentPlay, Task: match[1], StartedAt: now})
+			t.taskOpen = true
+		}
+	}
+}
+
+// commit records pending failures against the current task
+func (t *runTracker) commit() {
+	if len(t.pending) == 0 {
+		return
+	}
+	if t.failedTask == "" {
+		t.failedTask = t.currentTask
+		t.transient = t.pendingTransient
+	}
+	for _, host := range t.pending {
+		if !containsString(t.failedHosts, host) {
+			t.failedHosts = append(t.failedHosts, host)
+		}
+	}
+	t.pending = nil
+	t.pendingTransient = ""
+}
+
+// failure returns the first failing task and every host that failed
+func (t *runTracker) failure() (string, []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.commit()
+	return t.failedTask, append([]string(nil), t.failedHosts...)
+}
+
+// transientFailure returns the failing task and why its failure looks transient,
+// or an empty reason if the run should not be retried
+func (t *runTracker) transientFailure() (string, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.commit()
+	return t.failedTask, t.transient
+}
+
+// reset forgets the failures and recap of a failed attempt before it is retried.
+// Task timings are kept so the profile covers every attempt.
+func (t *runTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closeTask(time.Now())
+	t.failedTask, t.failedHosts, t.pending = "", nil, nil
+	t.transient, t.pendingTransient = "", ""
+	t.inRecap, t.sawRecap, t.recap = false, false, nil
+}
+
+// closeTask ends the timing of the task in progress
+func (t *runTracker) closeTask(now time.Time) {
+	if !t.taskOpen {
+		return
+	}
+	last := &t.timings[len(t.timings)-1]
+	last.DurationMs = now.Sub(last.StartedAt).Milliseconds()
+	t.taskOpen = false
+}
+
+// taskTimings returns the duration of every task seen so far, closing the one in progress
+func (t *runTracker) taskTimings() []TaskTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closeTask(time.Now())
+	return append([]TaskTiming(nil), t.timings...)
+}
+
+// addRecap records a host's recap counters, replacing any earlier recap for it
+func (t *runTracker) addRecap(host HostSummary) {
+	for i := range t.recap {
+		if t.recap[i].Host == host.Host {
+			t.recap[i] = host
+			return
+		}
+	}
+	t.recap = append(t.recap, host)
+}
+
+// summary returns the run's parsed PLAY RECAP
+func (t *runTracker) summary() RunSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return newRunSummary(append([]HostSummary(nil), t.recap...), t.sawRecap)
+}
+
+// recordUpgrade records the outcome of upgrading a component
+func (t *runTracker) recordUpgrade(result UpgradeResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.upgrades = append(t.upgrades, result)
+}
+
+// upgradeResults returns the outcome of every component upgrade in the run
+func (t *runTracker) upgradeResults() []UpgradeResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]UpgradeResult(nil), t.upgrades...)
+}
+
+// containsAll reports whether list contains every one of values
+func containsAll(list, values []string) bool {
+	for _, value := range values {
+		if !containsString(list, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// upgradePlaybook labels component upgrades in the run history. Like compose
+// deployments they always run over SSH, whichever backend is selected.
+const upgradePlaybook = "upgrade-components.yml"
+
+const (
+	// upgradeHealthTimeout is how long an upgraded container has to become healthy
+	upgradeHealthTimeout = 3 * time.Minute
+	// upgradeStableFor is how long a container with no healthcheck or URL must stay up
+	upgradeStableFor = 20 * time.Second
+	// upgradePollInterval is how often an upgraded container's health is checked
+	upgradePollInterval = 5 * time.Second
+)
+
+const (
+	upgradeUpgraded   = "upgraded"
+	upgradeUnchanged  = "unchanged"
+	upgradeRolledBack = "rolledBack"
+	upgradeSkipped    = "skipped"
+)
+
+// UpgradedContainer records the images a container moved between
+type UpgradedContainer struct {
+	Container       string `json:"container"`
+	Image           string `json:"image"`
+	PreviousImageID string `json:"previousImageId"`
+	PreviousDigest  string `json:"previousDigest,omitempty"`
+	ImageID         string `json:"imageId"`
+	Digest          string `json:"digest,omitempty"`
+}
+
+// UpgradeResult is the outcome of upgrading one component
+type UpgradeResult struct {
+	Component  string              `json:"component"`
+	Status     string              `json:"status"`
+	Containers []UpgradedContainer `json:"containers"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// UpgradeProgress is sent as an upgradeProgress event at each stage of an upgrade:
+// snapshot, pull, recreate, health, rollback and done
+type UpgradeProgress struct {
+	Component string `json:"component"`
+	Stage     string `json:"stage"`
+	Message   string `json:"message"`
+}
+
+// containerSnapshot is the state of a container before it is upgraded
+type containerSnapshot struct {
+	exists  bool
+	imageID string
+	digest  string
+	inspect containerInspect
+	// imageEnv is the environment the old image sets itself, which the replacement
+	// takes from the new image instead
+	imageEnv []string
+}
+
+// containerInspect is the part of docker inspect's output an upgrade carries over
+type containerInspect struct {
+	Config struct {
+		Env    []string          `json:"Env"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		Binds        []string                        `json:"Binds"`
+		NetworkMode  string                          `json:"NetworkMode"`
+		PortBindings map[string][]inspectPortBinding `json:"PortBindings"`
+		Memory       int64                           `json:"Memory"`
+		CapAdd       []string                        `json:"CapAdd"`
+		Sysctls      map[string]string               `json:"Sysctls"`
+		Devices      []struct {
+			PathOnHost        string `json:"PathOnHost"`
+			PathInContainer   string `json:"PathInContainer"`
+			CgroupPermissions string `json:"CgroupPermissions"`
+		} `json:"Devices"`
+		RestartPolicy struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount int    `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+	} `json:"HostConfig"`
+}
+
+// inspectPortBinding is one host side of a published port
+type inspectPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// parseContainerInspect reads the single container in docker inspect's JSON output
+func parseContainerInspect(data string) (containerInspect, error) {
+	var containers []containerInspect
+	if err := json.Unmarshal([]byte(data), &containers); err != nil {
+		return containerInspect{}, fmt.Errorf("failed to parse docker inspect output: %v", err)
+	}
+	if len(containers) != 1 {
+		return containerInspect{}, fmt.Errorf("docker inspect returned %d containers, want 1", len(containers))
+	}
+	return containers[0], nil
+}
+
+// env returns the container's environment as a map
+func (s containerSnapshot) env() map[string]string {
+	env := map[string]string{}
+	for _, entry := range s.inspect.Config.Env {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// replacement returns the spec that recreates the snapshotted container from
+// spec's image, keeping its ports, mounts, environment, memory limit, restart policy, network,
+// devices, capabilities and sysctls exactly as deployed. Environment entries the old
+// image set itself are left for the new image to set.
+func (s containerSnapshot) replacement(spec containerSpec) containerSpec {
+	host := s.inspect.HostConfig
+	replacement := containerSpec{
+		Name:         spec.Name,
+		Stack:        spec.Stack,
+		Image:        spec.Image,
+		Command:      spec.Command,
+		Env:          map[string]string{},
+		Volumes:      host.Binds,
+		Capabilities: host.CapAdd,
+		Sysctls:      host.Sysctls,
+		NetworkMode:  host.NetworkMode,
+		Restart:      host.RestartPolicy.Name,
+	}
+	if stack := s.inspect.Config.Labels["com.dockerizathinginator.stack"]; stack != "" {
+		replacement.Stack = stack
+	}
+	if replacement.Restart == "on-failure" && host.RestartPolicy.MaximumRetryCount > 0 {
+		replacement.Restart = fmt.Sprintf("on-failure:%d", host.RestartPolicy.MaximumRetryCount)
+	}
+	if replacement.Restart == "" {
+		replacement.Restart = "no"
+	}
+	if host.Memory > 0 {
+		replacement.Memory = fmt.Sprintf("%db", host.Memory)
+	}
+
+	for _, entry := range s.inspect.Config.Env {
+		if containsString(s.imageEnv, entry) {
+			continue
+		}
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			replacement.Env[key] = value
+		}
+	}
+
+	for _, container := range sortedKeys(host.PortBindings) {
+		target := strings.TrimSuffix(container, "/tcp")
+		for _, binding := range host.PortBindings[container] {
+			mapping := binding.HostPort + ":" + target
+			if binding.HostIP != "" {
+				mapping = binding.HostIP + ":" + mapping
+			}
+			replacement.Ports = append(replacement.Ports, mapping)
+		}
+	}
+
+	for _, device := range host.Devices {
+		mapping := device.PathOnHost + ":" + device.PathInContainer
+		if device.CgroupPermissions != "" && device.CgroupPermissions != "rwm" {
+			mapping += ":" + device.CgroupPermissions
+		}
+		replacement.Devices = append(replacement.Devices, mapping)
+	}
+	return replacement
+}
+
+// previousName is what a container is renamed to while its replacement is checked
+func previousName(container string) string {
+	return container + "-previous"
+}
+
+// upgradeComponents upgrades each listed component in catalog order, so
+// dependencies come up before the components that use them
+func (r *nativeRun) upgradeComponents() error {
+	ids, _ := r.listVar("upgrade_components")
+
+	var rolledBack []string
+	for _, component := range currentCatalog().Components {
+		if !containsString(ids, component.ID) {
+			continue
+		}
+		result, err := r.upgradeComponent(component)
+		if tracker := runTrackerFrom(r.ctx); tracker != nil {
+			tracker.recordUpgrade(result)
+		}
+		if err != nil {
+			return err
+		}
+		if result.Status == upgradeRolledBack {
+			rolledBack = append(rolledBack, component.Name)
+		}
+	}
+
+	if len(rolledBack) > 0 {
+		return fmt.Errorf("rolled back %s after the upgrade failed", strings.Join(rolledBack, ", "))
+	}
+	return nil
+}
+
+// upgradeComponent pulls new images for a component's containers and replaces the
+// containers whose image changed, recreating each from a snapshot of its current
+// settings so only the image differs. The previous containers are kept, stopped,
+// until the new ones are healthy, and are restored if they never become so.
+func (r *nativeRun) upgradeComponent(component CatalogComponent) (UpgradeResult, error) {
+	result := UpgradeResult{Component: component.ID, Status: upgradeUnchanged, Containers: []UpgradedContainer{}}
+	specs := make([]containerSpec, len(component.containers))
+	for i, spec := range component.containers {
+		specs[i] = r.customize(spec)
+	}
+
+	r.upgradeProgress(component, "snapshot", "Recording the current containers")
+	snapshots := map[string]containerSnapshot{}
+	if err := r.task(fmt.Sprintf("Snapshot %s containers", component.Name), func() (bool, error) {
+		for _, spec := range specs {
+			snapshot, err := r.snapshotContainer(spec.Name)
+			if err != nil {
+				return false, err
+			}
+			snapshots[spec.Name] = snapshot
+		}
+		return false, nil
+	}); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	for _, spec := range specs {
+		if !snapshots[spec.Name].exists {
+			result.Status = upgradeSkipped
+			result.Error = fmt.Sprintf("%s is not deployed", spec.Name)
+			r.upgradeProgress(component, "done", result.Error)
+			return result, nil
+		}
+	}
+
+	r.upgradeProgress(component, "pull", "Pulling the latest images")
+	var changed []containerSpec
+	if err := r.task(fmt.Sprintf("Pull %s images", component.Name), func() (bool, error) {
+		changed = nil
+		result.Containers = []UpgradedContainer{}
+		for _, spec := range specs {
+			if _, err := r.sudo("docker pull " + shellQuote(spec.Image)); err != nil {
+				return false, err
+			}
+			out, err := r.sudo("docker image inspect --format '{{.Id}}' " + shellQuote(spec.Image))
+			if err != nil {
+				return false, err
+			}
+			snapshot := snapshots[spec.Name]
+			upgraded := UpgradedContainer{
+				Container:       spec.Name,
+				Image:           spec.Image,
+				PreviousImageID: snapshot.imageID,
+				PreviousDigest:  snapshot.digest,
+				ImageID:         strings.TrimSpace(out),
+			}
+			upgraded.Digest, _ = r.imageDigest(upgraded.ImageID)
+			result.Containers = append(result.Containers, upgraded)
+			if upgraded.ImageID != snapshot.imageID {
+				changed = append(changed, spec)
+			}
+		}
+		return len(changed) > 0, nil
+	}); err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	if len(changed) == 0 {
+		r.upgradeProgress(component, "done", "Already running the latest images")
+		return result, nil
+	}
+
+	// The snapshots carry the passwords generated at deploy time into the new containers
+	for _, spec := range changed {
+		r.redactSnapshot(spec, snapshots[spec.Name].env())
+	}
+
+	r.upgradeProgress(component, "recreate", "Replacing the containers")
+	upgradeErr := r.task(fmt.Sprintf("Recreate %s containers", component.Name), func() (bool, error) {
+		for _, spec := range changed {
+			previous := shellQuote(previousName(spec.Name))
+			name := shellQuote(spec.Name)
+			// Snapshot values are literal, so placeholders in them are not expanded
+			replacement := snapshots[spec.Name].replacement(spec)
+			script := fmt.Sprintf("if ! docker inspect %[1]s >/dev/null 2>&1; then docker stop %[2]s >/dev/null && docker rename %[2]s %[1]s; fi && (docker rm -f %[2]s >/dev/null 2>&1 || true) && %[3]s",
+				previous, name, shellJoin(dockerRunArgs(replacement, func(s string) string { return s })))
+			if _, err := r.sudo(script); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+
+	if upgradeErr == nil {
+		r.upgradeProgress(component, "health", "Waiting for the new containers to become healthy")
+		upgradeErr = r.task(fmt.Sprintf("Wait for %s to become healthy", component.Name), func() (bool, error) {
+			for _, spec := range changed {
+				if err := r.waitHealthy(spec, componentHealthURL(component, spec)); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		})
+	}
+
+	if upgradeErr != nil {
+		result.Status = upgradeRolledBack
+		result.Error = upgradeErr.Error()
+		r.upgradeProgress(component, "rollback", "Restoring the previous containers: "+upgradeErr.Error())
+		if err := r.task(fmt.Sprintf("Roll back %s", component.Name), func() (bool, error) {
+			for _, spec := range changed {
+				previous := shellQuote(previousName(spec.Name))
+				name := shellQuote(spec.Name)
+				if _, err := r.sudo(fmt.Sprintf("if docker inspect %[1]s >/dev/null 2>&1; then (docker rm -f %[2]s >/dev/null 2>&1 || true) && docker rename %[1]s %[2]s && docker start %[2]s >/dev/null; fi", previous, name)); err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		}); err != nil {
+			result.Error = fmt.Sprintf("%s; rollback failed: %v", result.Error, err)
+			return result, err
+		}
+		r.upgradeProgress(component, "done", "Rolled back to the previous images")
+		return result, nil
+	}
+
+	result.Status = upgradeUpgraded
+	if err := r.task(fmt.Sprintf("Remove previous %s containers", component.Name), func() (bool, error) {
+		for _, spec := range changed {
+			if _, err := r.sudo("docker rm -f " + shellQuote(previousName(spec.Name)) + " >/dev/null 2>&1 || true"); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return result, err
+	}
+	r.upgradeProgress(component, "done", "Upgraded")
+	return result, nil
+}
+
+// snapshotContainer records a container's image, digest and settings. A leftover
+// previous container means an earlier upgrade was interrupted.
+func (r *nativeRun) snapshotContainer(name string) (containerSnapshot, error) {
+	out, err := r.sudo(fmt.Sprintf("docker inspect --format '{{.Image}}\t{{index .Config.Labels \"com.docker.compose.project\"}}' %s 2>/dev/null || true", shellQuote(name)))
+	if err != nil {
+		return containerSnapshot{}, err
+	}
+	fields := strings.SplitN(strings.TrimSpace(out), "\t", 2)
+	if fields[0] == "" {
+		return containerSnapshot{}, nil
+	}
+	if len(fields) == 2 && fields[1] != "" {
+		return containerSnapshot{}, fmt.Errorf("%s is managed by docker compose project %s; use docker compose pull instead", name, fields[1])
+	}
+	if out, _ := r.sudo(fmt.Sprintf("docker inspect %s >/dev/null 2>&1 && echo exists || true", shellQuote(previousName(name)))); strings.TrimSpace(out) == "exists" {
+		return containerSnapshot{}, fmt.Errorf("%s is left over from an interrupted upgrade; remove or rename it first", previousName(name))
+	}
+
+	snapshot := containerSnapshot{exists: true, imageID: fields[0]}
+	snapshot.digest, _ = r.imageDigest(snapshot.imageID)
+	out, err = r.sudo("docker inspect " + shellQuote(name))
+	if err != nil {
+		return containerSnapshot{}, err
+	}
+	if snapshot.inspect, err = parseContainerInspect(out); err != nil {
+		return containerSnapshot{}, err
+	}
+	imageEnv, err := r.sudo("docker image inspect --format '{{range .Config.Env}}{{println .}}{{end}}' " + shellQuote(snapshot.imageID))
+	if err != nil {
+		return containerSnapshot{}, err
+	}
+	for _, line := range strings.Split(imageEnv, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			snapshot.imageEnv = append(snapshot.imageEnv, line)
+		}
+	}
+	return snapshot, nil
+}
+
+// imageDigest returns the registry digest a local image was pulled by
+func (r *nativeRun) imageDigest(imageID string) (string, error) {
+	out, err := r.sudo("docker image inspect --format '{{range .RepoDigests}}{{println .}}{{end}}' " + shellQuote(imageID))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if _, digest, ok := strings.Cut(strings.TrimSpace(line), "@"); ok {
+			return digest, nil
+		}
+	}
+	return "", nil
+}
+
+// redactSnapshot registers the secrets in a container's current environment with
+// the run's redactor, since they end up on the replacement's docker run command:
+// values of generated {secret:...} placeholders and of secret-looking variables
+func (r *nativeRun) redactSnapshot(spec containerSpec, env map[string]string) {
+	redactor := redactorFrom(r.ctx)
+	if redactor == nil {
+		return
+	}
+	for key, current := range env {
+		match := placeholderPattern.FindStringSubmatch(spec.Env[key])
+		generated := match != nil && match[0] == spec.Env[key] && strings.HasPrefix(match[1], "secret:")
+		if generated || secretVarPattern.MatchString(key) {
+			redactor.add(current)
+		}
+	}
+}
+
+// componentHealthURL returns the URL to probe for a container's health from the
+// host itself: the component's health or web URL on the main container, with port
+// overrides applied
+func componentHealthURL(component CatalogComponent, spec containerSpec) string {
+	main := component.mainContainer()
+	target := component.HealthURL
+	if target == "" {
+		target = component.URL
+	}
+	if target == "" || spec.Name != main.Name {
+		return ""
+	}
+	u, err := url.Parse(strings.ReplaceAll(target, "{host}", "127.0.0.1"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	for i, mapping := range main.Ports {
+		if key, _ := parsePortMapping(mapping); key == port && i < len(spec.Ports) {
+			port, _ = parsePortMapping(spec.Ports[i])
+			break
+		}
+	}
+	u.Host = "127.0.0.1:" + port
+	return u.String()
+}
+
+// waitHealthy waits for a container's healthcheck to pass. Without one it probes
+// healthURL, or failing that waits for the container to stay running.
+func (r *nativeRun) waitHealthy(spec containerSpec, healthURL string) error {
+	deadline := time.Now().Add(upgradeHealthTimeout)
+	var runningSince time.Time
+	for {
+		out, err := r.sudo("docker inspect --format '{{.State.Status}} {{if .State.Health}}{{.State.Health.Status}}{{else}}none{{end}} {{.RestartCount}}' " + shellQuote(spec.Name))
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(out)
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected state for %s: %q", spec.Name, strings.TrimSpace(out))
+		}
+		status, health, restarts := fields[0], fields[1], fields[2]
+
+		switch {
+		case health == "healthy":
+			return nil
+		case health == "unhealthy":
+			return fmt.Errorf("%s reported unhealthy", spec.Name)
+		case status == "exited" || status == "dead":
+			return fmt.Errorf("%s stopped after starting", spec.Name)
+		case restarts != "0":
+			return fmt.Errorf("%s restarted %s times", spec.Name, restarts)
+		case health == "none" && status == "running" && healthURL != "":
+			code, _ := r.sudo("curl -ks -o /dev/null -w '%{http_code}' --max-time 5 " + shellQuote(healthURL) + " || true")
+			if code = strings.TrimSpace(code); code != "" && code != "000" && !strings.HasPrefix(code, "5") {
+				return nil
+			}
+		case health == "none" && status == "running":
+			if runningSince.IsZero() {
+				runningSince = time.Now()
+			} else if time.Since(runningSince) >= upgradeStableFor {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s did not become healthy within %s", spec.Name, upgradeHealthTimeout)
+		}
+		select {
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		case <-time.After(upgradePollInterval):
+		}
+	}
+}
+
+// upgradeProgress reports a stage of a component's upgrade to the frontend
+func (r *nativeRun) upgradeProgress(component CatalogComponent, stage, message string) {
+	// Output from the stage before must reach the frontend first
+	outputStreamFrom(r.ctx).flush()
+	runtime.EventsEmit(r.ctx, "upgradeProgress", UpgradeProgress{
+		Component: component.ID,
+		Stage:     stage,
+		Message:   fmt.Sprintf("%s: %s", component.Name, message),
+	})
+}
+
+// validateUpgradeInputs checks the inputs of a component upgrade
+func validateUpgradeInputs(host, user, volumePath string, components []string, overrides map[string]ComponentOverrides) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateVolumePath(v, "volumePath", volumePath)
+	if len(components) == 0 {
+		v.add("components", "select at least one component to upgrade")
+	}
+	for _, id := range components {
+		if _, ok := currentCatalog().component(id); !ok {
+			v.add("components."+id, "unknown component")
+		}
+	}
+	StackConfig{Overrides: overrides}.validate(v)
+	return v.err()
+}
+
+// UpgradeComponents pulls the latest image for each component's tag and recreates
+// its containers from their current docker inspect settings, so ports, mounts,
+// environment, memory limit and restart policy stay as deployed and only the image
+// changes; overrides only choose the image. A component whose new containers do
+// not become healthy is rolled back to its previous containers. Progress is sent
+// as upgradeProgress events and the outcome is recorded in the run history.
+func (a *App) UpgradeComponents(host, user, password, volumePath string, components []string, overrides map[string]ComponentOverrides) (RunSummary, error) {
+	if err := validateUpgradeInputs(host, user, volumePath, components, overrides); err != nil {
+		return RunSummary{}, err
+	}
+
+	vars := map[string]interface{}{
+		"volume_path":        volumePath,
+		"upgrade_components": components,
+	}
+	for _, component := range currentCatalog().Components {
+		componentOverrides := overrides[component.ID]
+		// Upgrading means moving off the pinned digest to the newest image for the tag
+		if containsString(components, component.ID) {
+			componentOverrides.Digest = ""
+		}
+		for key, value := range component.overrideVars(componentOverrides) {
+			vars[key] = value
+		}
+	}
+
+	runtime.EventsEmit(a.ctx, "updateProgress", "Upgrading components...")
+
+	return a.runJob(PlaybookJob{
+		Playbook:  upgradePlaybook,
+		Host:      host,
+		User:      user,
+		Password:  password,
+		ExtraVars: vars,
+	}, "")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const grafanaInspect = `[{
+  "Image": "sha256:old",
+  "Config": {
+    "Env": ["GF_SECURITY_ADMIN_PASSWORD=Xy7pQ2mN", "TZ=Europe/Berlin", "PATH=/usr/share/grafana/bin:/usr/bin", "GF_PATHS_DATA=/var/lib/grafana"],
+    "Labels": {"com.dockerizathinginator.stack": "iot"}
+  },
+  "HostConfig": {
+    "Binds": ["/mnt/docker/grafana:/var/lib/grafana", "/etc/localtime:/etc/localtime:ro"],
+    "NetworkMode": "docker_network",
+    "PortBindings": {"3000/tcp": [{"HostIp": "", "HostPort": "3001"}], "8125/udp": [{"HostIp": "127.0.0.1", "HostPort": "8125"}]},
+    "Memory": 268435456,
+    "Devices": [{"PathOnHost": "/dev/ttyUSB0", "PathInContainer": "/dev/ttyUSB0", "CgroupPermissions": "rwm"}],
+    "RestartPolicy": {"Name": "on-failure", "MaximumRetryCount": 3}
+  }
+}]`
+
+func TestContainerSnapshotReplacement(t *testing.T) {
+	inspect, err := parseContainerInspect(grafanaInspect)
+	if err != nil {
+		t.Fatalf("parseContainerInspect() error = %v", err)
+	}
+	snapshot := containerSnapshot{
+		exists:   true,
+		inspect:  inspect,
+		imageEnv: []string{"PATH=/usr/share/grafana/bin:/usr/bin", "GF_PATHS_DATA=/var/lib/grafana"},
+	}
+	// The catalog spec's own ports, env and volumes are ignored in favour of the snapshot
+	spec := containerSpec{
+		Name:    "grafana",
+		Stack:   "iot",
+		Image:   "grafana/grafana:11.0.0",
+		Ports:   []string{"3000:3000"},
+		Env:     map[string]string{"GF_SECURITY_ADMIN_PASSWORD": "{secret:grafana_password}"},
+		Volumes: []string{"{data}/grafana:/var/lib/grafana"},
+	}
+
+	want := containerSpec{
+		Name:        "grafana",
+		Stack:       "iot",
+		Image:       "grafana/grafana:11.0.0",
+		Ports:       []string{"3001:3000", "127.0.0.1:8125:8125/udp"},
+		Env:         map[string]string{"GF_SECURITY_ADMIN_PASSWORD": "Xy7pQ2mN", "TZ": "Europe/Berlin"},
+		Volumes:     []string{"/mnt/docker/grafana:/var/lib/grafana", "/etc/localtime:/etc/localtime:ro"},
+		Devices:     []string{"/dev/ttyUSB0:/dev/ttyUSB0"},
+		NetworkMode: "docker_network",
+		Restart:     "on-failure:3",
+		Memory:      "268435456b",
+	}
+	if got := snapshot.replacement(spec); !reflect.DeepEqual(got, want) {
+		t.Errorf("replacement() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestContainerSnapshotReplacementDefaults(t *testing.T) {
+	inspect, err := parseContainerInspect(`[{"Config": {"Env": null}, "HostConfig": {"NetworkMode": "host", "RestartPolicy": {"Name": ""}}}]`)
+	if err != nil {
+		t.Fatalf("parseContainerInspect() error = %v", err)
+	}
+	got := containerSnapshot{inspect: inspect}.replacement(containerSpec{Name: "pihole", Image: "pihole/pihole:2024.07.0"})
+	if got.Restart != "no" || got.Memory != "" || got.NetworkMode != "host" || len(got.Ports) != 0 {
+		t.Errorf("replacement() = %+v, want no restart, no memory limit and host networking", got)
+	}
+}
+
+func TestParseContainerInspectRejectsUnexpectedOutput(t *testing.T) {
+	for _, data := range []string{"", "[]", "[{}, {}]", "Error: No such object: grafana"} {
+		if _, err := parseContainerInspect(data); err == nil {
+			t.Errorf("parseContainerInspect(%q) accepted invalid output", data)
+		}
+	}
+}
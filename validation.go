@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	hostnamePattern  = regexp.MustCompile(`^(?i)[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+	usernamePattern  = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+	smbSharePattern  = regexp.MustCompile(`^[^\\/:*?"<>|\s][^\\/:*?"<>|]{0,79}$`)
+	smbUserPattern   = regexp.MustCompile(`^[^,=\s\\/]+(?:\\[^,=\s\\/]+)?$`)
+	volumeDirPattern = regexp.MustCompile(`^/[A-Za-z0-9._/-]*$`)
+	extraVarPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+)
+
+// protectedPaths may not be used as a volume path, since mounting over them breaks the OS
+var protectedPaths = []string{"/", "/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/proc", "/root", "/run", "/sbin", "/sys", "/tmp", "/usr", "/var"}
+
+// FieldError describes a problem with one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the field errors found in a binding's inputs
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error lists every field error in a single message
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+// add records a field error
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the collected errors, or nil if there were none
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// formatBindingError is the Wails error formatter. Validation errors reach the frontend
+// as {message, fields} so forms can highlight the offending inputs; everything else
+// stays a plain string.
+func formatBindingError(err error) any {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return map[string]interface{}{
+			"message": validationErr.Error(),
+			"fields":  validationErr.Fields,
+		}
+	}
+	return err.Error()
+}
+
+// validateConnection checks the SSH host and user
+func validateConnection(v *ValidationError, host, user string) {
+	hostname := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			v.add("host", "port must be between 1 and 65535")
+		}
+	}
+	if !validHost(hostname) {
+		v.add("host", "must be a host name or IP address")
+	}
+	if !usernamePattern.MatchString(user) {
+		v.add("user", "must be a valid Linux user name")
+	}
+}
+
+// validHost reports whether host is a DNS name or an IP address
+func validHost(host string) bool {
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return true
+	}
+	return len(host) <= 253 && hostnamePattern.MatchString(host)
+}
+
+// validateVolumePath checks that a volume path is a safe absolute directory on the Pi
+func validateVolumePath(v *ValidationError, field, volumePath string) {
+	switch {
+	case volumePath == "":
+		v.add(field, "is required")
+	case !strings.HasPrefix(volumePath, "/"):
+		v.add(field, "must be an absolute path")
+	case !volumeDirPattern.MatchString(volumePath):
+		v.add(field, "may only contain letters, digits, '.', '_', '-' and '/'")
+	case path.Clean(volumePath) != volumePath:
+		v.add(field, "must not contain '.' or '..' segments, repeated or trailing '/'")
+	case containsString(protectedPaths, path.Clean(volumePath)):
+		v.add(field, "%s is a system directory", path.Clean(volumePath))
+	}
+}
+
+// validate checks a storage configuration for its storage type
+func (c StorageConfig) validate(v *ValidationError) {
+	validateVolumePath(v, "volumePath", c.VolumePath)
+
+	switch c.Type {
+	case "usb":
+		switch {
+		case c.USBDevice == "":
+			v.add("usbDevice", "is required")
+		case strings.HasPrefix(c.USBDevice, "sda"):
+			v.add("usbDevice", "sda is the system disk and cannot be formatted")
+		case !usbDevicePattern.MatchString(c.USBDevice):
+			v.add("usbDevice", "must be a whole disk such as sdb, nvme0n1 or mmcblk1, not a partition")
+		}
+
+	case "nfs":
+		if !validHost(c.NFSServer) {
+			v.add("nfsServer", "must be a host name or IP address")
+		}
+		if !strings.HasPrefix(c.NFSPath, "/") || strings.ContainsAny(c.NFSPath, " \t\r\n,") {
+			v.add("nfsPath", "must be an absolute export path without spaces or commas")
+		}
+
+	case "cifs":
+		if !validHost(c.SMBServer) {
+			v.add("smbServer", "must be a host name or IP address")
+		}
+		if !smbSharePattern.MatchString(c.SMBShare) {
+			v.add("smbShare", "must be a share name without slashes or special characters")
+		}
+		// These end up in mount options and a credentials file, one per line
+		if c.SMBUsername != "" && !smbUserPattern.MatchString(c.SMBUsername) {
+			v.add("smbUsername", "must not contain spaces, commas or '='")
+		}
+		if strings.ContainsAny(c.SMBPassword, "\r\n\x00") {
+			v.add("smbPassword", "must not contain line breaks")
+		}
+
+	default:
+		v.add("type", "must be usb, nfs or cifs")
+	}
+}
+
+// knownComponentFlags returns the component flags the stack playbooks understand
+func knownComponentFlags() []string {
+	flags := make([]string, 0, len(currentCatalog().Components))
+	for _, component := range currentCatalog().Components {
+		flags = append(flags, component.Flag)
+	}
+	return flags
+}
+
+// validate checks that every stack and component key is one the catalog knows about
+func (c StackConfig) validate(v *ValidationError) {
+	for _, id := range sortedKeys(c.Stacks) {
+		if stack, ok := currentCatalog().stack(id); !ok || stack.Flag == "" {
+			v.add("stacks."+id, "unknown stack")
+		}
+	}
+
+	known := knownComponentFlags()
+	for _, key := range sortedKeys(c.Components) {
+		if !containsString(known, key) {
+			v.add("components."+key, "unknown component")
+		}
+	}
+
+	for _, id := range sortedKeys(c.Overrides) {
+		component, ok := currentCatalog().component(id)
+		if !ok {
+			v.add("overrides."+id, "unknown component")
+			continue
+		}
+		c.Overrides[id].validate(v, "overrides."+id, component)
+	}
+}
+
+// validateHostInputs checks the connection and, when given, the volume path
+func validateHostInputs(host, user string, volumePath *string) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	if volumePath != nil {
+		validateVolumePath(v, "volumePath", *volumePath)
+	}
+	return v.err()
+}
+
+// validateStorageInputs checks the inputs of a storage binding
+func validateStorageInputs(host, user string, config StorageConfig) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	config.validate(v)
+	return v.err()
+}
+
+// validateStackInputs checks the inputs of a stack deployment
+func validateStackInputs(host, user, volumePath string, config StackConfig) error {
+	v := &ValidationError{}
+	validateConnection(v, host, user)
+	validateVolumePath(v, "volumePath", volumePath)
+	config.validate(v)
+	return v.err()
+}
+
+// validateExtraVars checks caller-supplied extra-vars: names must be plain
+// variable names that cannot override connection settings, and no string in a
+// value, however deeply nested, may contain a template
+func validateExtraVars(v *ValidationError, field string, vars map[string]interface{}) {
+	for _, key := range sortedKeys(vars) {
+		switch {
+		case !extraVarPattern.MatchString(key):
+			v.add(field+"."+key, "must be a variable name of letters, digits and underscores")
+		case strings.HasPrefix(strings.ToLower(key), "ansible_"):
+			v.add(field+"."+key, "ansible_ variables cannot be set")
+		default:
+			validateExtraVarValue(v, field+"."+key, vars[key])
+		}
+	}
+}
+
+// validateExtraVarValue checks one extra-var value and everything inside it
+func validateExtraVarValue(v *ValidationError, field string, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		if templated(value) {
+			v.add(field, "must not contain {{, {%% or {#")
+		} else if strings.ContainsRune(value, 0) {
+			v.add(field, "must not contain NUL characters")
+		}
+	case []interface{}:
+		for i, item := range value {
+			validateExtraVarValue(v, fmt.Sprintf("%s[%d]", field, i), item)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			validateExtraVarValue(v, field+"."+key, value[key])
+		}
+	case nil, bool, float64:
+	default:
+		v.add(field, "must be a string, number, boolean, list or object")
+	}
+}
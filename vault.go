@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// vaultPasswordEnv carries the vault password to the password script in containerised runs
+const vaultPasswordEnv = "DOCKERIZATHINGINATOR_VAULT_PASSWORD"
+
+// vaultHeader is the ansible-vault 1.1 envelope header for AES256 payloads
+const vaultHeader = "$ANSIBLE_VAULT;1.1;AES256"
+
+// vaultPasswordScript prints the vault password from the environment for ansible-playbook
+const vaultPasswordScript = "#!/bin/sh\nprintf '%s' \"$" + vaultPasswordEnv + "\"\n"
+
+// secretVarPattern matches extra-var names whose values must never appear on a command line
+var secretVarPattern = regexp.MustCompile(`(?i)(password|passwd|passphrase|secret|token)`)
+
+// runVault holds the encrypted secret vars for one run and unlocks them for ansible-playbook
+type runVault struct {
+	file     string
+	password string
+	script   string
+	pipe     *os.File
+}
+
+// newRunVault encrypts secrets with a random password into the workspace's secrets directory
+func newRunVault(workspace *PlaybookWorkspace, secrets map[string]interface{}) (*runVault, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate vault password: %v", err)
+	}
+	vault := &runVault{password: hex.EncodeToString(key)}
+
+	plaintext, err := encodeVarsYAML(secrets)
+	if err != nil {
+		return nil, err
+	}
+	vaulttext, err := vaultEncrypt(plaintext, vault.password)
+	if err != nil {
+		return nil, err
+	}
+
+	vault.file, err = workspace.WriteSecret("secrets.vault.yml", vaulttext, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write vault: %v", err)
+	}
+	return vault, nil
+}
+
+// args returns the ansible-playbook arguments that load and unlock the vault.
+// Local runs read the password from an inherited pipe; containers cannot inherit
+// file descriptors, so they run a script that echoes it from the environment.
+func (v *runVault) args(workspace *PlaybookWorkspace, launcher *ansibleLauncher) ([]string, error) {
+	passwordFile := "/dev/fd/3"
+
+	if launcher.containerised() {
+		script, err := workspace.WriteSecret("vault-password.sh", []byte(vaultPasswordScript), 0700)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write vault password script: %v", err)
+		}
+		v.script = script
+		passwordFile = launcher.path(script)
+	}
+
+	return []string{
+		"-e", "@" + launcher.path(v.file),
+		"--vault-password-file", passwordFile,
+	}, nil
+}
+
+// attach hands the vault password to cmd without it appearing in its arguments
+func (v *runVault) attach(cmd *exec.Cmd) error {
+	if v.script != "" {
+		cmd.Env = append(cmd.Env, vaultPasswordEnv+"="+v.password)
+		return nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create vault password pipe: %v", err)
+	}
+	// The password is far smaller than the pipe buffer, so this never blocks
+	if _, err := writer.WriteString(v.password); err != nil {
+		reader.Close()
+		writer.Close()
+		return fmt.Errorf("failed to write vault password: %v", err)
+	}
+	writer.Close()
+
+	v.pipe = reader
+	cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+	return nil
+}
+
+// Close releases our end of the password pipe
+func (v *runVault) Close() {
+	if v.pipe != nil {
+		v.pipe.Close()
+		v.pipe = nil
+	}
+}
+
+// encodeVarsYAML renders vars as YAML, marking every string, however deeply
+// nested, !unsafe so they are never templated
+func encodeVarsYAML(vars map[string]interface{}) ([]byte, error) {
+	var b strings.Builder
+	for _, key := range sortedKeys(vars) {
+		value, err := decodeVar(vars[key])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s: %s\n", yamlScalar(key, true), yamlValue(value))
+	}
+	return []byte(b.String()), nil
+}
+
+// decodeVar round-trips value through JSON so structs, slices and numbers of any Go
+// type reduce to strings, json.Number, bool, nil, []interface{} and map[string]interface{}
+func decodeVar(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal vars: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to marshal vars: %v", err)
+	}
+	return decoded, nil
+}
+
+// hasNestedSecret reports whether a map anywhere inside value has a key that
+// secretVarPattern matches, like the password in a container's env overrides
+func hasNestedSecret(value interface{}) bool {
+	decoded, err := decodeVar(value)
+	if err != nil {
+		return false
+	}
+	found := false
+	walkSecrets(decoded, false, func(string) { found = true })
+	return found
+}
+
+// secretStrings returns the strings inside value that sit under a secret key, or
+// every string if the value as a whole is secret
+func secretStrings(value interface{}, secret bool) []string {
+	decoded, err := decodeVar(value)
+	if err != nil {
+		return nil
+	}
+	var values []string
+	walkSecrets(decoded, secret, func(s string) { values = append(values, s) })
+	return values
+}
+
+// walkSecrets calls found for each secret string in a decoded value
+func walkSecrets(value interface{}, secret bool, found func(string)) {
+	switch v := value.(type) {
+	case string:
+		if secret {
+			found(v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkSecrets(item, secret, found)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			walkSecrets(item, secret || secretVarPattern.MatchString(key), found)
+		}
+	}
+}
+
+// maskSecrets returns a copy of value with everything under a secret key replaced
+// by redactedMask, for storing where the real values must not go
+func maskSecrets(value interface{}) interface{} {
+	decoded, err := decodeVar(value)
+	if err != nil {
+		return redactedMask
+	}
+	return maskDecoded(decoded)
+}
+
+// maskDecoded masks the secrets in a decoded value
+func maskDecoded(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskDecoded(item)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if secretVarPattern.MatchString(key) {
+				masked[key] = redactedMask
+			} else {
+				masked[key] = maskDecoded(item)
+			}
+		}
+		return masked
+	default:
+		return v
+	}
+}
+
+// yamlValue renders a decoded JSON value as flow-style YAML with its strings tagged !unsafe
+func yamlValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return yamlScalar(v, false)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = yamlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			items = append(items, yamlScalar(key, true)+": "+yamlValue(v[key]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return "null"
+	}
+}
+
+// vaultEncrypt produces ansible-vault 1.1 AES256 ciphertext for plaintext
+func vaultEncrypt(plaintext []byte, password string) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate vault salt: %v", err)
+	}
+
+	// Same key derivation as ansible-vault: 32 byte cipher key, 32 byte HMAC key, 16 byte IV
+	derived := pbkdf2.Key([]byte(password), salt, 10000, 80, sha256.New)
+	cipherKey, hmacKey, iv := derived[:32], derived[32:64], derived[64:80]
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault cipher: %v", err)
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+
+	body := strings.Join([]string{
+		hex.EncodeToString(salt),
+		hex.EncodeToString(mac.Sum(nil)),
+		hex.EncodeToString(ciphertext),
+	}, "\n")
+	encoded := hex.EncodeToString([]byte(body))
+
+	var out strings.Builder
+	out.WriteString(vaultHeader + "\n")
+	for len(encoded) > 80 {
+		out.WriteString(encoded[:80] + "\n")
+		encoded = encoded[80:]
+	}
+	out.WriteString(encoded + "\n")
+	return []byte(out.String()), nil
+}
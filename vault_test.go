@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// vaultDecrypt reverses vaultEncrypt the way ansible-vault does, checking the HMAC
+func vaultDecrypt(t *testing.T, vaulttext []byte, password string) []byte {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(string(vaulttext)), "\n")
+	if lines[0] != vaultHeader {
+		t.Fatalf("header = %q, want %q", lines[0], vaultHeader)
+	}
+	for _, line := range lines[1:] {
+		if len(line) > 80 {
+			t.Fatalf("line of %d characters, want at most 80", len(line))
+		}
+	}
+
+	body, err := hex.DecodeString(strings.Join(lines[1:], ""))
+	if err != nil {
+		t.Fatalf("body is not hex: %v", err)
+	}
+	parts := strings.Split(string(body), "\n")
+	if len(parts) != 3 {
+		t.Fatalf("body has %d parts, want salt, hmac and ciphertext", len(parts))
+	}
+	salt, _ := hex.DecodeString(parts[0])
+	mac, _ := hex.DecodeString(parts[1])
+	ciphertext, _ := hex.DecodeString(parts[2])
+
+	derived := pbkdf2.Key([]byte(password), salt, 10000, 80, sha256.New)
+	check := hmac.New(sha256.New, derived[32:64])
+	check.Write(ciphertext)
+	if !hmac.Equal(check.Sum(nil), mac) {
+		t.Fatal("HMAC does not match")
+	}
+
+	block, _ := aes.NewCipher(derived[:32])
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, derived[64:80]).XORKeyStream(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		t.Fatalf("invalid padding %d", padding)
+	}
+	return plaintext[:len(plaintext)-padding]
+}
+
+func TestVaultEncryptRoundTrip(t *testing.T) {
+	for _, plaintext := range []string{
+		"",
+		"smb_password: !unsafe \"hunter2\"\n",
+		strings.Repeat("exactly sixteen!", 4),
+	} {
+		vaulttext, err := vaultEncrypt([]byte(plaintext), "run-password")
+		if err != nil {
+			t.Fatalf("vaultEncrypt() error = %v", err)
+		}
+		if bytes.Contains(vaulttext, []byte("hunter2")) {
+			t.Fatal("vault contains the plaintext")
+		}
+		if got := vaultDecrypt(t, vaulttext, "run-password"); string(got) != plaintext {
+			t.Errorf("decrypted %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestVaultEncryptSaltsEachRun(t *testing.T) {
+	first, _ := vaultEncrypt([]byte("same"), "password")
+	second, _ := vaultEncrypt([]byte("same"), "password")
+	if bytes.Equal(first, second) {
+		t.Error("two encryptions of the same plaintext are identical")
+	}
+}
+
+func TestEncodeVarsYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]interface{}
+		want string
+	}{
+		{"template is literal", map[string]interface{}{"path": "/mnt/{{ lookup('env', 'HOME') }}"}, `"path": !unsafe "/mnt/{{ lookup('env', 'HOME') }}"` + "\n"},
+		{"quotes and newlines", map[string]interface{}{"smb_password": "a\"b\nc: #d"}, `"smb_password": !unsafe "a\"b\nc: #d"` + "\n"},
+		{"html is not escaped", map[string]interface{}{"v": "<a&b>"}, `"v": !unsafe "<a&b>"` + "\n"},
+		{"scalars", map[string]interface{}{"flag": true, "count": 3, "ratio": 1.5, "none": nil}, "\"count\": 3\n\"flag\": true\n\"none\": null\n\"ratio\": 1.5\n"},
+		{"nested strings", map[string]interface{}{"list": []string{"a", "{{ b }}"}}, `"list": [!unsafe "a", !unsafe "{{ b }}"]` + "\n"},
+		{"nested maps", map[string]interface{}{"c": []interface{}{map[string]interface{}{"env": map[string]string{"TZ": "UTC"}, "default": false}}}, `"c": [{"default": false, "env": {"TZ": !unsafe "UTC"}}]` + "\n"},
+		{"empty", map[string]interface{}{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeVarsYAML(tt.vars)
+			if err != nil {
+				t.Fatalf("encodeVarsYAML() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("encodeVarsYAML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitVars(t *testing.T) {
+	job := PlaybookJob{
+		ExtraVars: map[string]interface{}{
+			"volume_path":    "/mnt/docker",
+			"smb_password":   "x",
+			"github_token":   "y",
+			"nextcloud_user": "admin",
+		},
+		SecretVars: []string{"nextcloud_user"},
+	}
+
+	job.ExtraVars["grafana_env"] = map[string]interface{}{"GF_SECURITY_ADMIN_PASSWORD": "z"}
+	job.ExtraVars["homeassistant_env"] = map[string]interface{}{"TZ": "UTC"}
+
+	plain, secret := job.splitVars()
+	if len(plain) != 2 || plain["volume_path"] != "/mnt/docker" || plain["homeassistant_env"] == nil {
+		t.Errorf("plain = %v, want volume_path and homeassistant_env", plain)
+	}
+	for _, key := range []string{"smb_password", "github_token", "nextcloud_user", "grafana_env"} {
+		if _, ok := secret[key]; !ok {
+			t.Errorf("secret vars are missing %s", key)
+		}
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	value := []interface{}{
+		map[string]interface{}{
+			"id":  "bridge",
+			"env": map[string]string{"DB_PASSWORD": "hunter2", "TZ": "UTC"},
+		},
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"id":  "bridge",
+			"env": map[string]interface{}{"DB_PASSWORD": redactedMask, "TZ": "UTC"},
+		},
+	}
+
+	if got := maskSecrets(value); !reflect.DeepEqual(got, want) {
+		t.Errorf("maskSecrets() = %v, want %v", got, want)
+	}
+	if !hasNestedSecret(value) || hasNestedSecret(map[string]string{"TZ": "UTC"}) {
+		t.Error("hasNestedSecret() did not tell the secret env from the plain one")
+	}
+}